@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aasheesh/logless/internal/agent"
+	"github.com/aasheesh/logless/internal/multiline"
+	"github.com/aasheesh/logless/pkg/logless"
+)
+
+var (
+	endpoint       = flag.String("endpoint", "http://localhost:8080", "LogLess server base URL")
+	paths          = flag.String("paths", "", "comma-separated glob patterns of files to tail, e.g. /var/log/*.log")
+	checkpointPath = flag.String("checkpoint", "logless-agent.checkpoint.json", "file that stores read offsets")
+	pollInterval   = flag.Duration("poll-interval", 250*time.Millisecond, "how often files are checked for new data")
+	rescanInterval = flag.Duration("rescan-interval", 5*time.Second, "how often globs are expanded to find new or rotated files")
+	batchSize      = flag.Int("batch-size", 500, "maximum number of lines per request")
+	flushInterval  = flag.Duration("flush-interval", time.Second, "maximum time a line waits before being sent")
+	bufferSize     = flag.Int("buffer-size", 10000, "number of lines buffered in memory before reading pauses")
+	maxRetries     = flag.Int("max-retries", 5, "retries per batch before the files are read again from the last checkpoint")
+	format         = flag.String("format", "raw", "line format: raw, docker (json-file), cri or auto")
+
+	multilineStart    = flag.String("multiline-start", "", "regex matching the first line of a multiline event")
+	multilineContinue = flag.String("multiline-continue", "", "regex matching continuation lines of a multiline event")
+	multilineMaxLines = flag.Int("multiline-max-lines", 500, "maximum number of lines joined into one event")
+	multilineTimeout  = flag.Duration("multiline-timeout", 2*time.Second, "flush a multiline event after this long without new lines")
+)
+
+func main() {
+	flag.Parse()
+
+	if *paths == "" {
+		log.Fatalf("No files to tail, set -paths")
+	}
+
+	client, err := logless.NewClient(logless.Config{
+		Endpoint:      *endpoint,
+		BufferSize:    *bufferSize,
+		BatchSize:     *batchSize,
+		FlushInterval: *flushInterval,
+		MaxRetries:    *maxRetries,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+
+	a, err := agent.New(agent.Config{
+		Paths:          strings.Split(*paths, ","),
+		CheckpointPath: *checkpointPath,
+		PollInterval:   *pollInterval,
+		RescanInterval: *rescanInterval,
+		Format:         *format,
+		Multiline: multiline.Config{
+			StartPattern:    *multilineStart,
+			ContinuePattern: *multilineContinue,
+			MaxLines:        *multilineMaxLines,
+			FlushTimeout:    *multilineTimeout,
+		},
+	}, client)
+	if err != nil {
+		log.Fatalf("Failed to initialize agent: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Agent is shipping %s to %s", *paths, *endpoint)
+	if err := a.Run(ctx); err != nil {
+		log.Printf("Agent stopped: %v", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := client.Close(shutdownCtx); err != nil {
+		log.Printf("Failed to flush remaining lines: %v", err)
+	}
+
+	stats := client.Stats()
+	log.Printf("Agent stopped, sent %d lines, dropped %d", stats.Sent, stats.Dropped())
+}
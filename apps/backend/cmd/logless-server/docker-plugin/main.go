@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/aasheesh/logless/internal/dockerdriver"
+	"github.com/aasheesh/logless/pkg/logless"
+)
+
+// Managed plugins are configured through environment variables
+// (docker plugin set logless LOGLESS_ENDPOINT=...), so each flag falls back
+// to one.
+var (
+	socketPath    = flag.String("socket", envOr("LOGLESS_SOCKET", "/run/docker/plugins/logless.sock"), "unix socket the Docker daemon connects to")
+	endpoint      = flag.String("endpoint", envOr("LOGLESS_ENDPOINT", "http://localhost:8080"), "LogLess server base URL")
+	historyLines  = flag.Int("history-lines", 1000, "recent lines kept per container for docker logs")
+	batchSize     = flag.Int("batch-size", 500, "maximum number of lines per request")
+	flushInterval = flag.Duration("flush-interval", time.Second, "maximum time a line waits before being sent")
+	bufferSize    = flag.Int("buffer-size", 10000, "number of lines buffered in memory before new ones are dropped")
+)
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func main() {
+	flag.Parse()
+
+	client, err := logless.NewClient(logless.Config{
+		Endpoint:      *endpoint,
+		BufferSize:    *bufferSize,
+		BatchSize:     *batchSize,
+		FlushInterval: *flushInterval,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+
+	driver := dockerdriver.NewDriver(dockerdriver.Config{HistoryLines: *historyLines}, client)
+
+	if err := os.MkdirAll(filepath.Dir(*socketPath), 0755); err != nil {
+		log.Fatalf("Failed to create socket directory: %v", err)
+	}
+	os.Remove(*socketPath)
+	listener, err := net.Listen("unix", *socketPath)
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", *socketPath, err)
+	}
+
+	server := &http.Server{Handler: dockerdriver.NewRouter(driver)}
+
+	go func() {
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		<-sigChan
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	log.Printf("Docker log driver listening on %s, shipping to %s", *socketPath, *endpoint)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Failed to serve plugin API: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := client.Close(ctx); err != nil {
+		log.Printf("Failed to flush remaining lines: %v", err)
+	}
+
+	stats := client.Stats()
+	log.Printf("Docker log driver stopped, sent %d lines, dropped %d", stats.Sent, stats.Dropped())
+}
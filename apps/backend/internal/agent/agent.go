@@ -0,0 +1,376 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/aasheesh/logless/internal/multiline"
+	"github.com/aasheesh/logless/pkg/logless"
+)
+
+type Config struct {
+	// Paths are glob patterns of the files to tail.
+	Paths              []string
+	CheckpointPath     string
+	PollInterval       time.Duration
+	RescanInterval     time.Duration
+	CheckpointInterval time.Duration
+	MaxLineSize        int
+	// Format is raw, docker (json-file), cri or auto. Container formats are
+	// unwrapped before multiline joining.
+	Format string
+	// Multiline joins continuation lines into one entry when enabled.
+	Multiline multiline.Config
+}
+
+// Agent tails the files matching the configured globs and ships every line
+// through the LogLess client.
+type Agent struct {
+	config     Config
+	client     *logless.Client
+	checkpoint *Checkpoint
+	files      map[string]*tailedFile
+	// rotated holds files that were renamed or removed from the globs. They
+	// are still read, as writers may not have reopened yet, until they stop
+	// growing and everything read from them has been flushed.
+	rotated   []*tailedFile
+	hostname  string
+	multiline *multiline.Rule
+}
+
+func New(config Config, client *logless.Client) (*Agent, error) {
+	if config.PollInterval <= 0 {
+		config.PollInterval = 250 * time.Millisecond
+	}
+	if config.RescanInterval <= 0 {
+		config.RescanInterval = 5 * time.Second
+	}
+	if config.CheckpointInterval <= 0 {
+		config.CheckpointInterval = 5 * time.Second
+	}
+	if config.MaxLineSize <= 0 {
+		config.MaxLineSize = 256 * 1024
+	}
+	if err := validFormat(config.Format); err != nil {
+		return nil, err
+	}
+
+	checkpoint, err := LoadCheckpoint(config.CheckpointPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+
+	a := &Agent{
+		config:     config,
+		client:     client,
+		checkpoint: checkpoint,
+		files:      make(map[string]*tailedFile),
+		hostname:   hostname,
+	}
+	if config.Multiline.Enabled() {
+		if a.multiline, err = multiline.NewRule(config.Multiline); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// Run tails until ctx is cancelled, then ships what is left and writes a
+// final checkpoint.
+func (a *Agent) Run(ctx context.Context) error {
+	a.scan()
+
+	poll := time.NewTicker(a.config.PollInterval)
+	defer poll.Stop()
+	rescan := time.NewTicker(a.config.RescanInterval)
+	defer rescan.Stop()
+	checkpoint := time.NewTicker(a.config.CheckpointInterval)
+	defer checkpoint.Stop()
+
+	for {
+		select {
+		case <-poll.C:
+			a.poll(ctx)
+		case <-rescan.C:
+			a.scan()
+		case <-checkpoint.C:
+			a.saveCheckpoint(ctx)
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			a.poll(shutdownCtx)
+			a.saveCheckpoint(shutdownCtx)
+
+			for _, t := range a.files {
+				t.file.Close()
+			}
+			for _, t := range a.rotated {
+				t.file.Close()
+			}
+			return nil
+		}
+	}
+}
+
+func (a *Agent) poll(ctx context.Context) {
+	now := time.Now()
+	for _, t := range a.files {
+		a.pollFile(ctx, t, now)
+	}
+	for _, t := range a.rotated {
+		a.pollFile(ctx, t, now)
+	}
+}
+
+func (a *Agent) pollFile(ctx context.Context, t *tailedFile, now time.Time) {
+	if err := t.readLines(a.config.MaxLineSize, a.emitter(ctx, t)); err != nil {
+		log.Printf("Failed to tail file: %v", err)
+	}
+	for _, assembler := range t.multiline {
+		if event, ok := assembler.FlushExpired(now); ok {
+			a.send(ctx, t, event)
+		}
+	}
+}
+
+// scan opens newly matched files and handles rotation. When a path points at
+// a different file than the one open (logrotate's rename mode), or no longer
+// matches, the old file moves to rotated and the new one is opened from the
+// start.
+func (a *Agent) scan() {
+	matched := make(map[string]bool)
+	for _, pattern := range a.config.Paths {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			log.Printf("Invalid path pattern %q: %v", pattern, err)
+			continue
+		}
+		for _, path := range paths {
+			matched[path] = true
+		}
+	}
+
+	for path, t := range a.files {
+		if !matched[path] {
+			a.rotated = append(a.rotated, t)
+			delete(a.files, path)
+			delete(a.checkpoint.Files, path)
+		}
+	}
+
+	for path := range matched {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		var state *FileState
+		if t, ok := a.files[path]; ok {
+			if getFileID(info) == t.id {
+				continue
+			}
+			log.Printf("File %s was rotated, finishing the old file", path)
+			a.rotated = append(a.rotated, t)
+			delete(a.files, path)
+		} else if saved, ok := a.checkpoint.Files[path]; ok {
+			state = &saved
+		}
+
+		if err := a.open(path, state); err != nil {
+			log.Printf("Failed to tail file: %v", err)
+		}
+	}
+}
+
+func (a *Agent) open(path string, state *FileState) error {
+	t, err := openTailedFile(path, state)
+	if err != nil {
+		return err
+	}
+	if a.config.Format != "" && a.config.Format != FormatRaw {
+		t.labels = containerLabels(path)
+	}
+	a.resetParsers(t)
+	a.files[path] = t
+	return nil
+}
+
+// resetParsers discards the container partials and multiline events held for
+// a file.
+func (a *Agent) resetParsers(t *tailedFile) {
+	if a.config.Format != "" && a.config.Format != FormatRaw {
+		t.container = newContainerParser(a.config.Format)
+	}
+	if a.multiline != nil {
+		t.multiline = make(map[string]*multiline.Assembler)
+	}
+}
+
+// rewind reopens every file at its last saved offset, discarding partial
+// lines and held events, so lines the client dropped are read and sent
+// again. Lines that did get through are sent twice. Rotated files can no
+// longer be opened by path and are moved back within the open file instead.
+func (a *Agent) rewind() {
+	for _, t := range a.rotated {
+		if err := t.reset(t.saved); err != nil {
+			log.Printf("Failed to tail file: %v", err)
+		}
+		t.idleOffset = -1
+		a.resetParsers(t)
+	}
+
+	for path, t := range a.files {
+		t.file.Close()
+		delete(a.files, path)
+
+		var state *FileState
+		if saved, ok := a.checkpoint.Files[path]; ok {
+			state = &saved
+		}
+		if err := a.open(path, state); err != nil {
+			log.Printf("Failed to tail file: %v", err)
+		}
+	}
+}
+
+// finishFile sends the lines still held for a file that will not grow
+// anymore.
+func (a *Agent) finishFile(ctx context.Context, t *tailedFile) {
+	t.finish(a.emitter(ctx, t))
+	if t.container != nil {
+		for _, line := range t.container.flush() {
+			a.join(ctx, t, line)
+		}
+	}
+	for _, assembler := range t.multiline {
+		if event, ok := assembler.Flush(); ok {
+			a.send(ctx, t, event)
+		}
+	}
+}
+
+func (a *Agent) emitter(ctx context.Context, t *tailedFile) func(text string, offset int64) {
+	return func(text string, offset int64) {
+		line := multiline.Line{Text: text, Offset: offset}
+		if t.container != nil {
+			var ok bool
+			if line, ok = t.container.parse(line); !ok {
+				return
+			}
+		}
+		a.join(ctx, t, line)
+	}
+}
+
+func (a *Agent) join(ctx context.Context, t *tailedFile, line multiline.Line) {
+	if t.multiline == nil {
+		a.send(ctx, t, line)
+		return
+	}
+	var stream string
+	if meta, ok := line.Meta.(lineMeta); ok {
+		stream = meta.stream
+	}
+	assembler, ok := t.multiline[stream]
+	if !ok {
+		assembler = a.multiline.NewAssembler()
+		t.multiline[stream] = assembler
+	}
+	for _, event := range assembler.Add(line, time.Now()) {
+		a.send(ctx, t, event)
+	}
+}
+
+// send waits for room in the client buffer, which stops the agent reading
+// while the server falls behind. A line that still cannot be queued holds the
+// file's checkpoint back so it is read again after a restart.
+func (a *Agent) send(ctx context.Context, t *tailedFile, line multiline.Line) {
+	if line.Text == "" {
+		return
+	}
+
+	entry := logless.Entry{
+		Message: line.Text,
+		Host:    a.hostname,
+		Source:  t.path,
+		Context: map[string]any{},
+	}
+	for key, value := range t.labels {
+		entry.Context[key] = value
+	}
+	if meta, ok := line.Meta.(lineMeta); ok {
+		entry.Timestamp = meta.timestamp
+		if meta.stream != "" {
+			entry.Context["stream"] = meta.stream
+		}
+	}
+	if err := a.client.SendWait(ctx, entry); err != nil {
+		if !t.failed || line.Offset < t.failedOffset {
+			t.failed, t.failedOffset = true, line.Offset
+		}
+	}
+}
+
+// saveCheckpoint waits for the client to send what has been read so far, so
+// the stored offsets never run ahead of the shipped lines. Lines still held by
+// a multiline assembler or an unfinished container partial line are read again
+// after a restart. When the client dropped lines, the files are read again
+// from the previous checkpoint instead. Rotated files that did not grow since
+// the previous checkpoint are finished and closed once the flush succeeds.
+func (a *Agent) saveCheckpoint(ctx context.Context) {
+	states := make(map[string]FileState, len(a.files))
+	for path, t := range a.files {
+		states[path] = FileState{ID: t.id, Offset: t.checkpointOffset(), Head: t.head}
+	}
+	// Rotated files are not stored, their offsets are only kept for rewind.
+	rotated := make(map[*tailedFile]int64, len(a.rotated))
+	idle := make(map[*tailedFile]bool)
+	for _, t := range a.rotated {
+		if t.offset == t.idleOffset {
+			a.finishFile(ctx, t)
+			idle[t] = true
+		}
+		t.idleOffset = t.offset
+		rotated[t] = t.checkpointOffset()
+	}
+
+	flushCtx, cancel := context.WithTimeout(ctx, a.config.CheckpointInterval)
+	defer cancel()
+	if err := a.client.Flush(flushCtx); err != nil {
+		switch {
+		case errors.Is(err, logless.ErrRejected):
+			// Reading them again would only be rejected again.
+			log.Printf("Some lines were rejected by the server: %v", err)
+		case errors.Is(err, logless.ErrDropped):
+			log.Printf("Lines were dropped, reading again from the last checkpoint")
+			a.rewind()
+			return
+		default:
+			log.Printf("Skipping checkpoint, flush did not complete: %v", err)
+			return
+		}
+	}
+
+	for path, state := range states {
+		a.checkpoint.Files[path] = state
+		a.files[path].saved = state.Offset
+	}
+	a.rotated = slices.DeleteFunc(a.rotated, func(t *tailedFile) bool {
+		t.saved = rotated[t]
+		if idle[t] && !t.failed {
+			t.file.Close()
+			return true
+		}
+		return false
+	})
+	if err := a.checkpoint.Save(); err != nil {
+		log.Printf("Failed to save checkpoint: %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aasheesh/logless/pkg/logless"
+)
+
+// recorder is a batch endpoint that keeps the messages it receives.
+type recorder struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	scanner := bufio.NewScanner(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for scanner.Scan() {
+		var entry logless.Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil {
+			r.messages = append(r.messages, entry.Message)
+		}
+	}
+	w.Write([]byte(`{}`))
+}
+
+// take returns the messages received so far, sorted, and forgets them.
+func (r *recorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	messages := r.messages
+	r.messages = nil
+	slices.Sort(messages)
+	return messages
+}
+
+func newTestAgent(t *testing.T, dir string, server *recorder) *Agent {
+	t.Helper()
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	client, err := logless.NewClient(logless.Config{Endpoint: ts.URL, DisableCompression: true, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close(t.Context()) })
+
+	a, err := New(Config{
+		Paths:          []string{filepath.Join(dir, "*.log")},
+		CheckpointPath: filepath.Join(dir, "checkpoint.json"),
+	}, client)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, tf := range a.files {
+			tf.file.Close()
+		}
+		for _, tf := range a.rotated {
+			tf.file.Close()
+		}
+	})
+	return a
+}
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func TestAgentRotationAndResume(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	server := &recorder{}
+	a := newTestAgent(t, dir, server)
+
+	appendFile(t, path, "a1\n")
+	a.scan()
+	a.poll(t.Context())
+
+	// logrotate renames the file; the writer keeps appending to it until it
+	// reopens the path.
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	appendFile(t, path+".1", "a2\n")
+	a.scan()
+	appendFile(t, path, "b1\n")
+	appendFile(t, path+".1", "a3\n")
+	a.scan()
+	a.poll(t.Context())
+
+	a.saveCheckpoint(t.Context())
+	if len(a.rotated) != 1 {
+		t.Fatalf("%d rotated files open, want 1 until it stops growing", len(a.rotated))
+	}
+	a.poll(t.Context())
+	a.saveCheckpoint(t.Context())
+	if len(a.rotated) != 0 {
+		t.Errorf("%d rotated files still open after going idle", len(a.rotated))
+	}
+	if got, want := server.take(), []string{"a1", "a2", "a3", "b1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("received %q, want %q", got, want)
+	}
+
+	// A restarted agent resumes the new file after what was shipped.
+	appendFile(t, path, "b2\n")
+	restarted := newTestAgent(t, dir, server)
+	restarted.scan()
+	restarted.poll(t.Context())
+	restarted.saveCheckpoint(t.Context())
+	if got, want := server.take(), []string{"b2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after restart received %q, want %q", got, want)
+	}
+}
+
+func TestAgentCopytruncateResume(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	server := &recorder{}
+	a := newTestAgent(t, dir, server)
+
+	appendFile(t, path, "old one\n")
+	a.scan()
+	a.poll(t.Context())
+	a.saveCheckpoint(t.Context())
+	server.take()
+
+	// Truncated and rewritten past the checkpoint while the agent was down.
+	if err := os.WriteFile(path, []byte("new one\nnew two\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	restarted := newTestAgent(t, dir, server)
+	restarted.scan()
+	restarted.poll(t.Context())
+	restarted.saveCheckpoint(t.Context())
+	if got, want := server.take(), []string{"new one", "new two"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("received %q, want %q", got, want)
+	}
+}
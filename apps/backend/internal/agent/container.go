@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aasheesh/logless/internal/multiline"
+)
+
+// Supported values of Config.Format.
+const (
+	FormatRaw    = "raw"
+	FormatDocker = "docker"
+	FormatCRI    = "cri"
+	FormatAuto   = "auto"
+)
+
+func validFormat(format string) error {
+	switch format {
+	case "", FormatRaw, FormatDocker, FormatCRI, FormatAuto:
+		return nil
+	}
+	return fmt.Errorf("unknown log format %q", format)
+}
+
+// lineMeta travels with each line as multiline.Line.Meta.
+type lineMeta struct {
+	timestamp time.Time
+	stream    string
+}
+
+// containerParser decodes Docker json-file and CRI log lines and reassembles
+// the partial records both runtimes write for long lines. Partials are kept
+// per stream because stdout and stderr are interleaved in the same file.
+type containerParser struct {
+	format  string
+	partial map[string]*multiline.Line
+}
+
+func newContainerParser(format string) *containerParser {
+	return &containerParser{format: format, partial: make(map[string]*multiline.Line)}
+}
+
+type dockerRecord struct {
+	Log    string    `json:"log"`
+	Stream string    `json:"stream"`
+	Time   time.Time `json:"time"`
+}
+
+// parse returns the complete line once the last fragment of it has been seen.
+// Lines that are not in the expected format are passed through unchanged.
+func (p *containerParser) parse(line multiline.Line) (multiline.Line, bool) {
+	format := p.format
+	if format == FormatAuto {
+		format = FormatCRI
+		if strings.HasPrefix(line.Text, "{") {
+			format = FormatDocker
+		}
+	}
+
+	switch format {
+	case FormatDocker:
+		var record dockerRecord
+		if err := json.Unmarshal([]byte(line.Text), &record); err != nil {
+			return line, true
+		}
+		// json-file splits lines longer than 16KiB; only the last piece ends
+		// with a newline.
+		partial := !strings.HasSuffix(record.Log, "\n")
+		text := strings.TrimSuffix(strings.TrimSuffix(record.Log, "\n"), "\r")
+		return p.assemble(line.Offset, text, lineMeta{timestamp: record.Time, stream: record.Stream}, partial)
+
+	case FormatCRI:
+		// <RFC3339Nano timestamp> <stream> <P|F> <content>
+		parts := strings.SplitN(line.Text, " ", 4)
+		if len(parts) < 3 || (parts[2] != "P" && !strings.HasPrefix(parts[2], "F")) {
+			return line, true
+		}
+		ts, err := time.Parse(time.RFC3339Nano, parts[0])
+		if err != nil {
+			return line, true
+		}
+		text := ""
+		if len(parts) == 4 {
+			text = parts[3]
+		}
+		return p.assemble(line.Offset, text, lineMeta{timestamp: ts, stream: parts[1]}, parts[2] == "P")
+	}
+
+	return line, true
+}
+
+func (p *containerParser) assemble(offset int64, text string, meta lineMeta, partial bool) (multiline.Line, bool) {
+	pending, ok := p.partial[meta.stream]
+	if !ok {
+		pending = &multiline.Line{Offset: offset, Meta: meta}
+	}
+	pending.Text += text
+
+	if partial {
+		p.partial[meta.stream] = pending
+		return multiline.Line{}, false
+	}
+	delete(p.partial, meta.stream)
+	return *pending, true
+}
+
+// pending returns the lowest offset of any unfinished partial line.
+func (p *containerParser) pending() (int64, bool) {
+	var (
+		lowest int64
+		found  bool
+	)
+	for _, line := range p.partial {
+		if !found || line.Offset < lowest {
+			lowest, found = line.Offset, true
+		}
+	}
+	return lowest, found
+}
+
+// flush returns unfinished partial lines, used when a file goes away.
+func (p *containerParser) flush() []multiline.Line {
+	var lines []multiline.Line
+	for stream, line := range p.partial {
+		lines = append(lines, *line)
+		delete(p.partial, stream)
+	}
+	return lines
+}
+
+var (
+	// /var/log/containers/<pod>_<namespace>_<container>-<id>.log
+	kubernetesContainerPath = regexp.MustCompile(`^([^_]+)_([^_]+)_(.+)-([0-9a-f]{64})\.log$`)
+	// /var/log/pods/<namespace>_<pod>_<uid>/<container>/<restart>.log
+	kubernetesPodPath = regexp.MustCompile(`/pods/([^_/]+)_([^_/]+)_([^_/]+)/([^/]+)/[^/]+\.log$`)
+	// /var/lib/docker/containers/<id>/<id>-json.log
+	dockerContainerPath = regexp.MustCompile(`/containers/([0-9a-f]{64})/[0-9a-f]{64}-json\.log`)
+)
+
+// containerLabels extracts pod, namespace, container name and container ID
+// from the well-known container log locations.
+func containerLabels(path string) map[string]string {
+	labels := make(map[string]string)
+
+	if m := kubernetesContainerPath.FindStringSubmatch(filepath.Base(path)); m != nil {
+		labels["pod"] = m[1]
+		labels["namespace"] = m[2]
+		labels["container"] = m[3]
+		labels["container_id"] = m[4]
+	} else if m := kubernetesPodPath.FindStringSubmatch(filepath.ToSlash(path)); m != nil {
+		labels["namespace"] = m[1]
+		labels["pod"] = m[2]
+		labels["pod_uid"] = m[3]
+		labels["container"] = m[4]
+	} else if m := dockerContainerPath.FindStringSubmatch(filepath.ToSlash(path)); m != nil {
+		labels["container_id"] = m[1]
+	}
+
+	return labels
+}
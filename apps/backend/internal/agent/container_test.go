@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aasheesh/logless/internal/multiline"
+)
+
+func TestContainerParser(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 123000000, time.UTC)
+
+	tests := []struct {
+		name   string
+		format string
+		lines  []string
+		want   []multiline.Line
+	}{
+		{
+			name:   "docker json-file",
+			format: FormatDocker,
+			lines:  []string{`{"log":"hello\r\n","stream":"stdout","time":"2024-05-01T10:00:00.123Z"}`},
+			want:   []multiline.Line{{Text: "hello", Meta: lineMeta{timestamp: ts, stream: "stdout"}}},
+		},
+		{
+			name:   "docker partial pieces",
+			format: FormatDocker,
+			lines: []string{
+				`{"log":"hel","stream":"stdout","time":"2024-05-01T10:00:00.123Z"}`,
+				`{"log":"err\n","stream":"stderr","time":"2024-05-01T10:00:00.123Z"}`,
+				`{"log":"lo\n","stream":"stdout","time":"2024-05-01T10:00:00.123Z"}`,
+			},
+			want: []multiline.Line{
+				{Text: "err", Offset: 1, Meta: lineMeta{timestamp: ts, stream: "stderr"}},
+				{Text: "hello", Meta: lineMeta{timestamp: ts, stream: "stdout"}},
+			},
+		},
+		{
+			name:   "cri with partial",
+			format: FormatCRI,
+			lines: []string{
+				"2024-05-01T10:00:00.123Z stdout P hel",
+				"2024-05-01T10:00:00.123Z stdout F lo world",
+				"2024-05-01T10:00:00.123Z stderr F",
+			},
+			want: []multiline.Line{
+				{Text: "hello world", Meta: lineMeta{timestamp: ts, stream: "stdout"}},
+				{Text: "", Offset: 2, Meta: lineMeta{timestamp: ts, stream: "stderr"}},
+			},
+		},
+		{
+			name:   "auto detects both",
+			format: FormatAuto,
+			lines: []string{
+				`{"log":"a\n","stream":"stdout","time":"2024-05-01T10:00:00.123Z"}`,
+				"2024-05-01T10:00:00.123Z stderr F b",
+			},
+			want: []multiline.Line{
+				{Text: "a", Meta: lineMeta{timestamp: ts, stream: "stdout"}},
+				{Text: "b", Offset: 1, Meta: lineMeta{timestamp: ts, stream: "stderr"}},
+			},
+		},
+		{
+			name:   "unrecognised lines pass through",
+			format: FormatCRI,
+			lines:  []string{"plain text", "not-a-time stdout F x"},
+			want:   []multiline.Line{{Text: "plain text"}, {Text: "not-a-time stdout F x", Offset: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newContainerParser(tt.format)
+			var got []multiline.Line
+			for i, text := range tt.lines {
+				if line, ok := p.parse(multiline.Line{Text: text, Offset: int64(i)}); ok {
+					got = append(got, line)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if _, ok := p.pending(); ok {
+				t.Error("partial lines left over")
+			}
+		})
+	}
+}
+
+func TestContainerParserPending(t *testing.T) {
+	p := newContainerParser(FormatCRI)
+	p.parse(multiline.Line{Text: "2024-05-01T10:00:00Z stdout P a", Offset: 10})
+	p.parse(multiline.Line{Text: "2024-05-01T10:00:00Z stderr P b", Offset: 20})
+	if offset, ok := p.pending(); !ok || offset != 10 {
+		t.Errorf("pending = %d, %v, want 10, true", offset, ok)
+	}
+	if lines := p.flush(); len(lines) != 2 {
+		t.Errorf("flush returned %d lines, want 2", len(lines))
+	}
+	if _, ok := p.pending(); ok {
+		t.Error("partial lines left after flush")
+	}
+}
+
+func TestContainerLabels(t *testing.T) {
+	id := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		path string
+		want map[string]string
+	}{
+		{
+			path: "/var/log/containers/web-1_prod_nginx-" + id + ".log",
+			want: map[string]string{"pod": "web-1", "namespace": "prod", "container": "nginx", "container_id": id},
+		},
+		{
+			path: "/var/log/pods/prod_web-1_uid-1/nginx/0.log",
+			want: map[string]string{"namespace": "prod", "pod": "web-1", "pod_uid": "uid-1", "container": "nginx"},
+		},
+		{
+			path: "/var/lib/docker/containers/" + id + "/" + id + "-json.log",
+			want: map[string]string{"container_id": id},
+		},
+		{path: "/var/log/app.log", want: map[string]string{}},
+	}
+	for _, tt := range tests {
+		if got := containerLabels(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("containerLabels(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
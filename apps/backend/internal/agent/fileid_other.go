@@ -0,0 +1,16 @@
+//go:build !unix
+
+package agent
+
+import "os"
+
+// fileID is empty on platforms without inodes; rotation is then detected by
+// size alone.
+type fileID struct {
+	Dev   uint64 `json:"dev"`
+	Inode uint64 `json:"inode"`
+}
+
+func getFileID(info os.FileInfo) fileID {
+	return fileID{}
+}
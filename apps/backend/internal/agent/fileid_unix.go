@@ -0,0 +1,22 @@
+//go:build unix
+
+package agent
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID identifies a file independently of its path so renames by logrotate
+// can be told apart from truncation.
+type fileID struct {
+	Dev   uint64 `json:"dev"`
+	Inode uint64 `json:"inode"`
+}
+
+func getFileID(info os.FileInfo) fileID {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return fileID{Dev: uint64(stat.Dev), Inode: uint64(stat.Ino)}
+	}
+	return fileID{}
+}
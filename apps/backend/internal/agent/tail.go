@@ -0,0 +1,214 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/aasheesh/logless/internal/multiline"
+)
+
+const readBufferSize = 32 * 1024
+
+// headSize is how many leading bytes of a file are remembered to recognise it
+// again. A file truncated in place and rewritten past the read position keeps
+// its inode and looks like it only grew; its first bytes give it away.
+const headSize = 64
+
+// tailedFile follows one open file. offset is the read position in the file
+// and consumed the position just after the last complete line; the bytes in
+// between are held in partial.
+type tailedFile struct {
+	path     string
+	file     *os.File
+	id       fileID
+	offset   int64
+	consumed int64
+	partial  []byte
+	buf      []byte
+	// head holds up to headSize leading bytes of the file.
+	head []byte
+	// saved is the offset stored by the last successful checkpoint, and
+	// idleOffset the read position seen by the one before it; a rotated file
+	// whose offset did not move between checkpoints is finished.
+	saved      int64
+	idleOffset int64
+
+	// container is nil for plain log files.
+	container *containerParser
+	// labels holds the container metadata derived from the path.
+	labels map[string]string
+	// multiline is nil unless the agent joins continuation lines. It holds
+	// one assembler per stream so interleaved stdout and stderr lines of a
+	// container file are not joined together.
+	multiline map[string]*multiline.Assembler
+
+	// failed is set once a line could not be handed to the client;
+	// failedOffset is where the earliest such line starts.
+	failed       bool
+	failedOffset int64
+}
+
+// openTailedFile opens path and resumes from state when it still describes
+// the same file, with the same inode and first bytes, otherwise it starts from
+// the beginning.
+func openTailedFile(path string, state *FileState) (*tailedFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	t := &tailedFile{path: path, file: file, id: getFileID(info), buf: make([]byte, readBufferSize), idleOffset: -1}
+	if state != nil && state.ID == t.id && state.Offset <= info.Size() {
+		head, err := t.readHead(len(state.Head))
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if bytes.Equal(head, state.Head) {
+			if err := t.reset(state.Offset); err != nil {
+				file.Close()
+				return nil, err
+			}
+			t.head = head
+			t.saved = state.Offset
+		}
+	}
+	return t, nil
+}
+
+// readHead returns up to n leading bytes of the file without moving the read
+// position.
+func (t *tailedFile) readHead(n int) ([]byte, error) {
+	head := make([]byte, n)
+	n, err := t.file.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read %s: %w", t.path, err)
+	}
+	return head[:n], nil
+}
+
+// reset moves the read position to offset, discarding the partial line.
+func (t *tailedFile) reset(offset int64) error {
+	if _, err := t.file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek %s: %w", t.path, err)
+	}
+	t.offset = offset
+	t.consumed = offset
+	t.partial = nil
+	t.failed = false
+	return nil
+}
+
+// readLines reads everything appended since the last call and emits each
+// complete line together with the offset it starts at. A file that shrank
+// below the read position or whose first bytes changed was truncated in place
+// (logrotate copytruncate) and is read again from the start.
+func (t *tailedFile) readLines(maxLineSize int, emit func(line string, offset int64)) error {
+	info, err := t.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", t.path, err)
+	}
+	truncated := info.Size() < t.offset
+	if !truncated && len(t.head) > 0 {
+		head, err := t.readHead(len(t.head))
+		if err != nil {
+			return err
+		}
+		truncated = !bytes.Equal(head, t.head)
+	}
+	if truncated {
+		log.Printf("File %s was truncated, reading from the start", t.path)
+		if err := t.reset(0); err != nil {
+			return err
+		}
+		t.head = nil
+	}
+	defer t.updateHead()
+
+	for {
+		n, err := t.file.Read(t.buf)
+		if n > 0 {
+			t.offset += int64(n)
+			t.split(t.buf[:n], maxLineSize, emit)
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", t.path, err)
+		}
+	}
+}
+
+// updateHead extends head up to headSize once that much has been read.
+func (t *tailedFile) updateHead() {
+	if len(t.head) >= headSize || t.offset <= int64(len(t.head)) {
+		return
+	}
+	if head, err := t.readHead(int(min(t.offset, headSize))); err == nil {
+		t.head = head
+	}
+}
+
+func (t *tailedFile) split(data []byte, maxLineSize int, emit func(line string, offset int64)) {
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			t.partial = append(t.partial, data...)
+			if len(t.partial) >= maxLineSize {
+				// Emit oversized lines in pieces rather than buffering forever.
+				emit(string(t.partial), t.consumed)
+				t.consumed += int64(len(t.partial))
+				t.partial = nil
+			}
+			return
+		}
+
+		line := data[:i]
+		if len(t.partial) > 0 {
+			line = append(t.partial, line...)
+			t.partial = nil
+		}
+		emit(string(bytes.TrimSuffix(line, []byte("\r"))), t.consumed)
+		t.consumed += int64(len(line)) + 1
+		data = data[i+1:]
+	}
+}
+
+// finish emits any unterminated last line.
+func (t *tailedFile) finish(emit func(line string, offset int64)) {
+	if len(t.partial) > 0 {
+		emit(string(t.partial), t.consumed)
+		t.consumed += int64(len(t.partial))
+		t.partial = nil
+	}
+}
+
+// checkpointOffset is where reading resumes so that no line that was read but
+// not yet handed to the client, or that failed to be, is skipped.
+func (t *tailedFile) checkpointOffset() int64 {
+	offset := t.consumed
+	if t.container != nil {
+		if pending, ok := t.container.pending(); ok && pending < offset {
+			offset = pending
+		}
+	}
+	for _, assembler := range t.multiline {
+		if pending, ok := assembler.Pending(); ok && pending < offset {
+			offset = pending
+		}
+	}
+	if t.failed && t.failedOffset < offset {
+		offset = t.failedOffset
+	}
+	return offset
+}
@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type emitted struct {
+	text   string
+	offset int64
+}
+
+func readAll(t *testing.T, tf *tailedFile, maxLineSize int) []emitted {
+	t.Helper()
+	var lines []emitted
+	err := tf.readLines(maxLineSize, func(text string, offset int64) {
+		lines = append(lines, emitted{text, offset})
+	})
+	if err != nil {
+		t.Fatalf("readLines: %v", err)
+	}
+	return lines
+}
+
+func openTemp(t *testing.T, content string, state *FileState) (string, *tailedFile) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	tf, err := openTailedFile(path, state)
+	if err != nil {
+		t.Fatalf("openTailedFile: %v", err)
+	}
+	t.Cleanup(func() { tf.file.Close() })
+	return path, tf
+}
+
+func TestReadLines(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		maxLineSize int
+		want        []emitted
+		consumed    int64
+	}{
+		{
+			name:     "complete lines",
+			content:  "one\r\ntwo\n",
+			want:     []emitted{{"one", 0}, {"two", 5}},
+			consumed: 9,
+		},
+		{
+			name:     "partial last line is held",
+			content:  "one\ntw",
+			want:     []emitted{{"one", 0}},
+			consumed: 4,
+		},
+		{
+			name:        "oversized unterminated line is not held",
+			content:     "abcdefgh",
+			maxLineSize: 4,
+			want:        []emitted{{"abcdefgh", 0}},
+			consumed:    8,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, tf := openTemp(t, tt.content, nil)
+			if tt.maxLineSize == 0 {
+				tt.maxLineSize = 1024
+			}
+			if got := readAll(t, tf, tt.maxLineSize); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if tf.consumed != tt.consumed {
+				t.Errorf("consumed = %d, want %d", tf.consumed, tt.consumed)
+			}
+		})
+	}
+}
+
+func TestReadLinesCopytruncate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rewrite string
+		want    []emitted
+	}{
+		{name: "shrunk", rewrite: "new\n", want: []emitted{{"new", 0}}},
+		{
+			name:    "regrown past the old offset",
+			rewrite: "rewritten line one\nrewritten line two\n",
+			want:    []emitted{{"rewritten line one", 0}, {"rewritten line two", 19}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, tf := openTemp(t, "first line\nsecond\n", nil)
+			readAll(t, tf, 1024)
+
+			// Truncate in place, keeping the inode.
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0)
+			if err != nil {
+				t.Fatalf("truncate: %v", err)
+			}
+			f.WriteString(tt.rewrite)
+			f.Close()
+
+			if got := readAll(t, tf, 1024); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenTailedFileResume(t *testing.T) {
+	const content = "one\ntwo\nthree\n"
+	probe, tf := openTemp(t, content, nil)
+	readAll(t, tf, 1024)
+	id, head := tf.id, tf.head
+	if string(head) != content {
+		t.Fatalf("head = %q, want the whole short file", head)
+	}
+
+	tests := []struct {
+		name  string
+		state FileState
+		want  string
+	}{
+		{name: "same file", state: FileState{ID: id, Offset: 4, Head: head}, want: "two"},
+		{name: "checkpoint without head", state: FileState{ID: id, Offset: 4}, want: "two"},
+		{name: "different first bytes", state: FileState{ID: id, Offset: 4, Head: []byte("other\n")}, want: "one"},
+		{name: "offset past the end", state: FileState{ID: id, Offset: 100, Head: head}, want: "one"},
+		{name: "different inode", state: FileState{ID: fileID{Inode: ^uint64(0)}, Offset: 4, Head: head}, want: "one"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf, err := openTailedFile(probe, &tt.state)
+			if err != nil {
+				t.Fatalf("openTailedFile: %v", err)
+			}
+			defer tf.file.Close()
+			lines := readAll(t, tf, 1024)
+			if len(lines) == 0 || lines[0].text != tt.want {
+				t.Errorf("first line = %v, want %q", lines, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeadStopsAtHeadSize(t *testing.T) {
+	_, tf := openTemp(t, strings.Repeat("x", headSize*2)+"\n", nil)
+	readAll(t, tf, 1<<20)
+	if len(tf.head) != headSize {
+		t.Errorf("head holds %d bytes, want %d", len(tf.head), headSize)
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aasheesh/logless/internal/models"
+)
+
+const maxBatchEntries = 10000
+
+// BatchLogHandler accepts either a JSON array of log entries or
+// newline-delimited JSON and reports an accepted/rejected result per item.
+func (h *LogHandler) BatchLogHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(w, r, maxDecompressedBodySize)
+	if err != nil {
+		respondWithBodyError(w, err)
+		return
+	}
+
+	items, err := splitBatch(body, r.Header.Get("Content-Type"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(items) == 0 {
+		respondWithError(w, http.StatusBadRequest, "batch is empty")
+		return
+	}
+	if len(items) > maxBatchEntries {
+		respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("batch exceeds %d entries", maxBatchEntries))
+		return
+	}
+
+	response := models.BatchIngestResponse{Results: make([]models.IngestResult, len(items))}
+
+	var (
+		entries []models.LogEntry
+		indexes []int
+	)
+	for i, item := range items {
+		response.Results[i].Index = i
+
+		var entry models.LogEntry
+		if err := json.Unmarshal(item, &entry); err != nil {
+			response.Results[i].Status = "rejected"
+			response.Results[i].Error = "invalid log entry: " + err.Error()
+			continue
+		}
+		if err := entry.Validate(); err != nil {
+			response.Results[i].Status = "rejected"
+			response.Results[i].Error = err.Error()
+			continue
+		}
+
+		entries = append(entries, entry)
+		indexes = append(indexes, i)
+	}
+
+	h.publishBatch(w, response, entries, indexes)
+}
+
+// publishBatch sends the valid entries, whose positions in the request are
+// given by indexes, and responds with the per-item results.
+func (h *LogHandler) publishBatch(w http.ResponseWriter, response models.BatchIngestResponse, entries []models.LogEntry, indexes []int) {
+	for i, err := range h.producer.SendLogs(entries) {
+		result := &response.Results[indexes[i]]
+		if err != nil {
+			result.Status = "rejected"
+			result.Error = "failed to send log to Kafka"
+			continue
+		}
+		result.Status = "accepted"
+	}
+
+	for _, result := range response.Results {
+		if result.Status == "accepted" {
+			response.Accepted++
+		} else {
+			response.Rejected++
+		}
+	}
+
+	code := http.StatusCreated
+	if response.Rejected > 0 {
+		code = http.StatusMultiStatus
+	}
+	respondWithJSON(w, code, response)
+}
+
+// splitBatch breaks a request body into raw JSON documents. A body whose first
+// non-space byte is '[' is treated as a JSON array, anything else as NDJSON.
+func splitBatch(body []byte, contentType string) ([]json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	if trimmed[0] == '[' && !strings.Contains(contentType, "ndjson") {
+		var items []json.RawMessage
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, errors.New("invalid JSON array payload")
+		}
+		return items, nil
+	}
+
+	var items []json.RawMessage
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	scanner.Buffer(make([]byte, 64*1024), len(trimmed)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		items = append(items, json.RawMessage(append([]byte(nil), line...)))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NDJSON payload: %w", err)
+	}
+	return items, nil
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	producer "github.com/aasheesh/logless/internal/kafka"
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// newTestProducer returns a producer for a broker that does not exist.
+// Messages only go to the local queue, which holds queueSize of them before
+// Produce fails.
+func newTestProducer(t *testing.T, queueSize int) *producer.LogProducer {
+	t.Helper()
+	p, err := kafka.NewProducer(&kafka.ConfigMap{
+		"bootstrap.servers":            "127.0.0.1:1",
+		"queue.buffering.max.messages": queueSize,
+		"log_level":                    0,
+	})
+	if err != nil {
+		t.Fatalf("kafka.NewProducer: %v", err)
+	}
+	t.Cleanup(p.Close)
+	return producer.NewLogProducer(p)
+}
+
+func TestSplitBatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        []string
+		wantErr     bool
+	}{
+		{name: "array", body: ` [{"a":1}, {"b":2}] `, want: []string{`{"a":1}`, `{"b":2}`}},
+		{name: "ndjson", body: "{\"a\":1}\n\n{\"b\":2}\r\n", want: []string{`{"a":1}`, `{"b":2}`}},
+		{name: "ndjson of arrays", body: "[1]\n[2]", contentType: "application/x-ndjson", want: []string{`[1]`, `[2]`}},
+		{name: "empty", body: "  \n", want: nil},
+		{name: "broken array", body: `[{"a":1}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := splitBatch([]byte(tt.body), tt.contentType)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", items)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitBatch: %v", err)
+			}
+			var got []string
+			for _, item := range items {
+				got = append(got, string(item))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchLogHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		queueSize int
+		code      int
+		statuses  []string
+	}{
+		{
+			name:     "all accepted",
+			body:     `[{"message":"a"},{"message":"b","level":"warn"}]`,
+			code:     http.StatusCreated,
+			statuses: []string{"accepted", "accepted"},
+		},
+		{
+			name:     "invalid items rejected",
+			body:     "{\"message\":\"a\"}\n{\"message\":\"\"}\nnot json\n{\"message\":\"d\",\"timestamp\":\"yesterday\"}",
+			code:     http.StatusMultiStatus,
+			statuses: []string{"accepted", "rejected", "rejected", "rejected"},
+		},
+		{
+			name:      "kafka queue full",
+			body:      `[{"message":"a"},{"message":"b"}]`,
+			queueSize: 1,
+			code:      http.StatusMultiStatus,
+			statuses:  []string{"accepted", "rejected"},
+		},
+		{name: "empty", body: "[]", code: http.StatusBadRequest},
+		{name: "malformed array", body: "[", code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.queueSize == 0 {
+				tt.queueSize = 100
+			}
+			h := NewLogHandler(nil, newTestProducer(t, tt.queueSize))
+			rec := httptest.NewRecorder()
+			h.BatchLogHandler(rec, httptest.NewRequest(http.MethodPost, "/api/logs/batch", bytes.NewBufferString(tt.body)))
+
+			if rec.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.code, rec.Body)
+			}
+			if tt.statuses == nil {
+				return
+			}
+			var response models.BatchIngestResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			var statuses []string
+			accepted := 0
+			for i, result := range response.Results {
+				if result.Index != i {
+					t.Errorf("result %d has index %d", i, result.Index)
+				}
+				if result.Status == "rejected" && result.Error == "" {
+					t.Errorf("result %d is rejected without an error", i)
+				}
+				if result.Status == "accepted" {
+					accepted++
+				}
+				statuses = append(statuses, result.Status)
+			}
+			if !reflect.DeepEqual(statuses, tt.statuses) {
+				t.Errorf("statuses = %q, want %q", statuses, tt.statuses)
+			}
+			if response.Accepted != accepted || response.Rejected != len(statuses)-accepted {
+				t.Errorf("counts = %d/%d, want %d/%d", response.Accepted, response.Rejected, accepted, len(statuses)-accepted)
+			}
+		})
+	}
+}
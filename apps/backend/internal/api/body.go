@@ -0,0 +1,41 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/aasheesh/logless/internal/utils"
+)
+
+const (
+	maxWireBodySize         = 10 << 20
+	maxDecompressedBodySize = 64 << 20
+)
+
+// readBody returns the request body with any Content-Encoding removed. Both the
+// compressed and decompressed sizes are capped.
+func readBody(w http.ResponseWriter, r *http.Request, maxSize int64) ([]byte, error) {
+	wire := http.MaxBytesReader(w, r.Body, maxWireBodySize)
+
+	body, err := utils.NewDecompressingReader(wire, r.Header.Get("Content-Encoding"), maxSize)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
+// respondWithBodyError maps a readBody failure to the matching status code.
+func respondWithBodyError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, utils.ErrUnsupportedEncoding):
+		respondWithError(w, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, utils.ErrDecompressedTooLarge), errors.As(err, &maxBytesErr):
+		respondWithError(w, http.StatusRequestEntityTooLarge, "request body too large")
+	default:
+		respondWithError(w, http.StatusBadRequest, "invalid request body")
+	}
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aasheesh/logless/internal/deadletter"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultDeadLetterLimit = 50
+	maxDeadLetterLimit     = 500
+)
+
+type DeadLetterHandler struct {
+	reader *deadletter.Reader
+	token  string
+}
+
+// NewDeadLetterHandler requires "Authorization: Bearer <token>" on every
+// request, since letters hold raw payloads and redriving writes to the log
+// topic. An empty token rejects everything.
+func NewDeadLetterHandler(reader *deadletter.Reader, token string) *DeadLetterHandler {
+	return &DeadLetterHandler{reader: reader, token: token}
+}
+
+// ListDeadLetters handles GET /api/dead-letters?partition=&offset=&limit=.
+func (h *DeadLetterHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	query := r.URL.Query()
+
+	partition := int64(-1)
+	if value := query.Get("partition"); value != "" {
+		p, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || p < 0 {
+			respondWithError(w, http.StatusBadRequest, "invalid partition")
+			return
+		}
+		partition = p
+	}
+
+	offset := int64(-1)
+	if value := query.Get("offset"); value != "" {
+		o, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || o < 0 {
+			respondWithError(w, http.StatusBadRequest, "invalid offset")
+			return
+		}
+		offset = o
+	}
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultDeadLetterLimit
+	}
+	limit = min(limit, maxDeadLetterLimit)
+
+	letters, err := h.reader.List(int32(partition), offset, limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to read dead letters")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]any{"dead_letters": letters})
+}
+
+// GetDeadLetter handles GET /api/dead-letters/{partition}/{offset}.
+func (h *DeadLetterHandler) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	partition, offset, ok := deadLetterPosition(w, r)
+	if !ok {
+		return
+	}
+
+	letter, err := h.reader.Get(partition, offset)
+	if err != nil {
+		respondWithDeadLetterError(w, err, "failed to read dead letter")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, letter)
+}
+
+// RedriveDeadLetter handles POST /api/dead-letters/{partition}/{offset}/redrive.
+// A non-empty body replaces the original message. Redriving the same letter
+// twice is refused unless force=true is given.
+func (h *DeadLetterHandler) RedriveDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	partition, offset, ok := deadLetterPosition(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := readBody(w, r, maxDecompressedBodySize)
+	if err != nil {
+		respondWithBodyError(w, err)
+		return
+	}
+	if len(body) == 0 {
+		body = nil
+	}
+
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+
+	tp, err := h.reader.Redrive(partition, offset, body, force)
+	if err != nil {
+		respondWithDeadLetterError(w, err, "failed to redrive dead letter")
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, map[string]any{
+		"message":   "dead letter redriven",
+		"topic":     *tp.Topic,
+		"partition": tp.Partition,
+		"offset":    int64(tp.Offset),
+	})
+}
+
+func (h *DeadLetterHandler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if h.token == "" || !strings.EqualFold(scheme, "Bearer") || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(h.token)) != 1 {
+		respondWithError(w, http.StatusUnauthorized, "invalid or missing admin token")
+		return false
+	}
+	return true
+}
+
+func deadLetterPosition(w http.ResponseWriter, r *http.Request) (int32, int64, bool) {
+	vars := mux.Vars(r)
+	partition, err := strconv.ParseInt(vars["partition"], 10, 32)
+	if err != nil || partition < 0 {
+		respondWithError(w, http.StatusBadRequest, "invalid partition")
+		return 0, 0, false
+	}
+	offset, err := strconv.ParseInt(vars["offset"], 10, 64)
+	if err != nil || offset < 0 {
+		respondWithError(w, http.StatusBadRequest, "invalid offset")
+		return 0, 0, false
+	}
+	return int32(partition), offset, true
+}
+
+func respondWithDeadLetterError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, deadletter.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, deadletter.ErrAlreadyRedriven):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// The requests below are all refused before the reader is used, so the
+// handlers run without Kafka.
+func TestDeadLetterHandlerRefuses(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		target string
+		header string
+		code   int
+	}{
+		{name: "no token configured", method: http.MethodGet, target: "/api/dead-letters", header: "Bearer ", code: http.StatusUnauthorized},
+		{name: "missing header", token: "secret", method: http.MethodGet, target: "/api/dead-letters", code: http.StatusUnauthorized},
+		{name: "wrong scheme", token: "secret", method: http.MethodGet, target: "/api/dead-letters", header: "Basic secret", code: http.StatusUnauthorized},
+		{name: "wrong token", token: "secret", method: http.MethodGet, target: "/api/dead-letters/0/1", header: "Bearer secre", code: http.StatusUnauthorized},
+		{name: "redrive without token", token: "secret", method: http.MethodPost, target: "/api/dead-letters/0/1/redrive", code: http.StatusUnauthorized},
+		{name: "negative partition filter", token: "secret", method: http.MethodGet, target: "/api/dead-letters?partition=-1", header: "Bearer secret", code: http.StatusBadRequest},
+		{name: "partition out of range", token: "secret", method: http.MethodGet, target: "/api/dead-letters?partition=4294967296", header: "Bearer secret", code: http.StatusBadRequest},
+		{name: "invalid offset filter", token: "secret", method: http.MethodGet, target: "/api/dead-letters?offset=x", header: "Bearer secret", code: http.StatusBadRequest},
+		{name: "invalid partition", token: "secret", method: http.MethodGet, target: "/api/dead-letters/p/1", header: "bearer secret", code: http.StatusBadRequest},
+		{name: "negative offset", token: "secret", method: http.MethodPost, target: "/api/dead-letters/0/-5/redrive", header: "Bearer secret", code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewDeadLetterHandler(nil, tt.token)
+			router := mux.NewRouter()
+			router.HandleFunc("/api/dead-letters", h.ListDeadLetters).Methods(http.MethodGet)
+			router.HandleFunc("/api/dead-letters/{partition}/{offset}", h.GetDeadLetter).Methods(http.MethodGet)
+			router.HandleFunc("/api/dead-letters/{partition}/{offset}/redrive", h.RedriveDeadLetter).Methods(http.MethodPost)
+
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.code {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.code, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aasheesh/logless/internal/elastic"
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// ElasticBulkHandler implements the Elasticsearch _bulk API. The target index
+// comes from the URL or from each action line and is kept as the service tag.
+func (h *LogHandler) ElasticBulkHandler(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	body, err := readBody(w, r, maxDecompressedBodySize)
+	if err != nil {
+		respondWithBodyError(w, err)
+		return
+	}
+
+	items, err := elastic.ParseBulk(body, mux.Vars(r)["index"])
+	if err != nil {
+		respondWithElasticError(w, http.StatusBadRequest, "illegal_argument_exception", err.Error())
+		return
+	}
+
+	var (
+		entries []models.LogEntry
+		indexes []int
+	)
+	results := make([]elastic.BulkItemResult, len(items))
+	for i, item := range items {
+		results[i] = elastic.BulkItemResult{Index: item.Index, ID: item.ID}
+		if item.Err != nil {
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = item.Err
+			continue
+		}
+		entries = append(entries, item.Entry)
+		indexes = append(indexes, i)
+	}
+
+	for i, err := range h.producer.SendLogs(entries) {
+		result := &results[indexes[i]]
+		if err != nil {
+			// 429 makes the shippers retry just this document.
+			result.Status = http.StatusTooManyRequests
+			result.Error = &elastic.ItemError{Type: "es_rejected_execution_exception", Reason: "failed to send log to Kafka"}
+			continue
+		}
+		result.Version = 1
+		result.Result = "created"
+		result.Status = http.StatusCreated
+	}
+
+	response := elastic.BulkResponse{Items: make([]map[string]elastic.BulkItemResult, len(items))}
+	for i, item := range items {
+		response.Items[i] = map[string]elastic.BulkItemResult{item.Action: results[i]}
+		if results[i].Error != nil {
+			response.Errors = true
+		}
+	}
+	response.Took = time.Since(start).Milliseconds()
+
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// ElasticInfoHandler answers the version probe Beats and Logstash send before
+// their first bulk request.
+func (h *LogHandler) ElasticInfoHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	respondWithJSON(w, http.StatusOK, elastic.NewInfoResponse())
+}
+
+func respondWithElasticError(w http.ResponseWriter, code int, errType, reason string) {
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	respondWithJSON(w, code, map[string]any{
+		"error":  elastic.ItemError{Type: errType, Reason: reason},
+		"status": code,
+	})
+}
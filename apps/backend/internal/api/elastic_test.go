@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aasheesh/logless/internal/elastic"
+	"github.com/gorilla/mux"
+)
+
+func TestElasticBulkHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		queueSize int
+		code      int
+		errors    bool
+		statuses  []int
+	}{
+		{
+			name:     "created",
+			body:     "{\"index\":{}}\n{\"message\":\"a\"}\n{\"create\":{\"_index\":\"other\"}}\n{\"message\":\"b\"}\n",
+			code:     http.StatusOK,
+			statuses: []int{http.StatusCreated, http.StatusCreated},
+		},
+		{
+			name:     "item errors",
+			body:     "{\"delete\":{\"_id\":\"1\"}}\n{\"index\":{}}\n{\"message\":\"a\",\"@timestamp\":\"never\"}\n{\"index\":{}}\n{\"message\":\"b\"}\n",
+			code:     http.StatusOK,
+			errors:   true,
+			statuses: []int{http.StatusBadRequest, http.StatusBadRequest, http.StatusCreated},
+		},
+		{
+			name:      "kafka queue full",
+			body:      "{\"index\":{}}\n{\"message\":\"a\"}\n{\"index\":{}}\n{\"message\":\"b\"}\n",
+			queueSize: 1,
+			code:      http.StatusOK,
+			errors:    true,
+			statuses:  []int{http.StatusCreated, http.StatusTooManyRequests},
+		},
+		{name: "malformed action", body: "{\"index\":\n", code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.queueSize == 0 {
+				tt.queueSize = 100
+			}
+			router := mux.NewRouter()
+			router.HandleFunc("/{index}/_bulk", NewLogHandler(nil, newTestProducer(t, tt.queueSize)).ElasticBulkHandler)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/web/_bulk", bytes.NewBufferString(tt.body)))
+
+			if rec.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.code, rec.Body)
+			}
+			if rec.Header().Get("X-Elastic-Product") != "Elasticsearch" {
+				t.Error("X-Elastic-Product header missing")
+			}
+			if tt.statuses == nil {
+				return
+			}
+			var response elastic.BulkResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if response.Errors != tt.errors {
+				t.Errorf("errors = %v, want %v", response.Errors, tt.errors)
+			}
+			if len(response.Items) != len(tt.statuses) {
+				t.Fatalf("%d items, want %d", len(response.Items), len(tt.statuses))
+			}
+			for i, item := range response.Items {
+				for _, result := range item {
+					if result.Status != tt.statuses[i] {
+						t.Errorf("item %d status = %d, want %d", i, result.Status, tt.statuses[i])
+					}
+					if result.Index == "" || result.ID == "" {
+						t.Errorf("item %d lacks index or id: %+v", i, result)
+					}
+				}
+			}
+		})
+	}
+}
@@ -4,12 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aasheesh/logless/internal/domain"
 	producer "github.com/aasheesh/logless/internal/kafka"
 	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/severity"
 	"github.com/gorilla/mux"
 )
 
@@ -25,8 +28,19 @@ func NewLogHandler(service *domain.LogService, producer *producer.LogProducer) *
 func (h *LogHandler) LogHandler(w http.ResponseWriter, r *http.Request) {
 	// ctx := r.Context()
 
+	body, err := readBody(w, r, maxDecompressedBodySize)
+	if err != nil {
+		respondWithBodyError(w, err)
+		return
+	}
+
+	if isTextContentType(r.Header.Get("Content-Type")) {
+		h.textLogHandler(w, body)
+		return
+	}
+
 	var entry models.LogEntry
-	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+	if err := json.Unmarshal(body, &entry); err != nil {
 		respondWithError(w, http.StatusBadRequest, "invalid request payload")
 		return
 	}
@@ -51,7 +65,13 @@ func (h *LogHandler) GetPaginatedLogs(w http.ResponseWriter, r *http.Request) {
 		pageSize = 10
 	}
 
-	response, err := h.service.GetPaginatedLogs(ctx, page, pageSize)
+	filter, err := logFilter(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := h.service.GetPaginatedLogs(ctx, filter, page, pageSize)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -140,29 +160,37 @@ func (h *LogHandler) GetDateLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	 startTime, err := time.Parse(time.RFC3339, startDate)
-    if err != nil {
-        http.Error(w, "Invalid startDate format (use RFC3339)", http.StatusBadRequest)
-        return
-    }
-    
-    endTime, err := time.Parse(time.RFC3339, endDate)
-    if err != nil {
-        http.Error(w, "Invalid endDate format (use RFC3339)", http.StatusBadRequest)
-        return
-    }
-    
-    ctx := r.Context()
-    response, err := h.service.GetDateRangeLogs(ctx, startTime, endTime, page, pageSize)
-    if err != nil {
-        http.Error(w, fmt.Sprintf("Error fetching logs: %v", err), http.StatusInternalServerError)
-        return
-    }
-    
-    w.Header().Set("Content-Type", "application/json")
-    if err := json.NewEncoder(w).Encode(response); err != nil {
-        http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-    }
+	startTime, err := time.Parse(time.RFC3339, startDate)
+	if err != nil {
+		http.Error(w, "Invalid startDate format (use RFC3339)", http.StatusBadRequest)
+		return
+	}
+
+	endTime, err := time.Parse(time.RFC3339, endDate)
+	if err != nil {
+		http.Error(w, "Invalid endDate format (use RFC3339)", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := logFilter(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Start = startTime
+	filter.End = endTime
+
+	ctx := r.Context()
+	response, err := h.service.GetDateRangeLogs(ctx, filter, page, pageSize)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching logs: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
 
 }
 
@@ -178,6 +206,58 @@ func (h *LogHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, health)
 }
 
+// logFilter reads the field filters shared by the log listing endpoints,
+// e.g. ?service=checkout&environment=prod&level=>=warn. The level accepts a
+// comparison prefix; min_level=warn is a shorthand for level=>=warn.
+// field.<path>=value matches a context value, such as field.status=500 for
+// a status extracted by a grok processor.
+func logFilter(query url.Values) (models.LogFilter, error) {
+	filter := models.LogFilter{
+		Service:     query.Get("service"),
+		Environment: query.Get("environment"),
+		Host:        query.Get("host"),
+		TraceID:     query.Get("trace_id"),
+		SpanID:      query.Get("span_id"),
+		Source:      query.Get("source"),
+	}
+
+	if level := query.Get("level"); level != "" {
+		levelRange, err := severity.ParseCondition(level)
+		if err != nil {
+			return models.LogFilter{}, fmt.Errorf("invalid level filter: %w", err)
+		}
+		filter.Level = levelRange
+	}
+	if minLevel := query.Get("min_level"); minLevel != "" {
+		minSeverity, ok := severity.Parse(minLevel)
+		if !ok {
+			return models.LogFilter{}, fmt.Errorf("invalid min_level %q", minLevel)
+		}
+		filter.Level.Min = max(filter.Level.Min, minSeverity)
+	}
+	if minRepeat := query.Get("min_repeat_count"); minRepeat != "" {
+		n, err := strconv.Atoi(minRepeat)
+		if err != nil || n < 1 {
+			return models.LogFilter{}, fmt.Errorf("invalid min_repeat_count %q", minRepeat)
+		}
+		filter.MinRepeatCount = n
+	}
+	for key, values := range query {
+		path, ok := strings.CutPrefix(key, "field.")
+		if !ok {
+			continue
+		}
+		if path == "" || strings.HasPrefix(path, ".") || strings.HasSuffix(path, ".") {
+			return models.LogFilter{}, fmt.Errorf("invalid field filter %q", key)
+		}
+		if filter.Fields == nil {
+			filter.Fields = make(map[string]string)
+		}
+		filter.Fields[path] = values[0]
+	}
+	return filter, nil
+}
+
 func respondWithError(w http.ResponseWriter, code int, message string) {
 	respondWithJSON(w, code, map[string]string{"error": message})
 }
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	"github.com/aasheesh/logless/internal/loki"
+	"github.com/aasheesh/logless/internal/models"
+)
+
+// LokiPushHandler implements Loki's push API so Promtail, Grafana Agent and
+// Fluent Bit can ship to LogLess unchanged. Protobuf bodies are snappy
+// compressed by the protocol itself; JSON bodies may use Content-Encoding.
+func (h *LogHandler) LokiPushHandler(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "" {
+		mediaType = "application/x-protobuf"
+	}
+	if mediaType != "application/x-protobuf" && mediaType != "application/json" {
+		respondWithError(w, http.StatusUnsupportedMediaType, "content type must be application/x-protobuf or application/json")
+		return
+	}
+
+	body, err := readBody(w, r, maxDecompressedBodySize)
+	if err != nil {
+		respondWithBodyError(w, err)
+		return
+	}
+
+	var entries []models.LogEntry
+	if mediaType == "application/x-protobuf" {
+		entries, err = loki.DecodeProtobuf(body, maxDecompressedBodySize)
+	} else {
+		entries, err = loki.DecodeJSON(body)
+	}
+	if errors.Is(err, loki.ErrPayloadTooLarge) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	for _, err := range h.producer.SendLogs(entries) {
+		if err != nil {
+			respondWithError(w, http.StatusServiceUnavailable, "failed to send logs to Kafka")
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/otlp"
+)
+
+// OTLPLogsHandler implements the OTLP/HTTP logs endpoint for both the
+// application/x-protobuf and application/json encodings.
+func (h *LogHandler) OTLPLogsHandler(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/x-protobuf" && mediaType != "application/json" {
+		respondWithError(w, http.StatusUnsupportedMediaType, "content type must be application/x-protobuf or application/json")
+		return
+	}
+
+	body, err := readBody(w, r, maxDecompressedBodySize)
+	if err != nil {
+		respondWithBodyError(w, err)
+		return
+	}
+
+	var entries []models.LogEntry
+	if mediaType == "application/x-protobuf" {
+		entries, err = otlp.DecodeProtobuf(body)
+	} else {
+		entries, err = otlp.DecodeJSON(body)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	for _, err := range h.producer.SendLogs(entries) {
+		if err != nil {
+			// 503 tells OTLP exporters the export is retryable.
+			respondWithError(w, http.StatusServiceUnavailable, "failed to send logs to Kafka")
+			return
+		}
+	}
+
+	// An empty ExportLogsServiceResponse encodes to zero bytes in protobuf
+	// and to "{}" in JSON.
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(http.StatusOK)
+	if mediaType == "application/json" {
+		w.Write([]byte("{}"))
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	producer "github.com/aasheesh/logless/internal/kafka"
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/multiline"
+	"github.com/aasheesh/logless/internal/splunk"
+)
+
+// HECHandler serves the Splunk HTTP Event Collector endpoints.
+type HECHandler struct {
+	producer  *producer.LogProducer
+	tokens    [][]byte
+	insecure  bool
+	multiline *multiline.Rule
+}
+
+// NewHECHandler accepts only the listed tokens, and rejects every request
+// when there are none unless insecure is set, in which case any token is
+// accepted. A non-nil multiline rule joins continuation lines on the raw
+// endpoint.
+func NewHECHandler(producer *producer.LogProducer, tokens []string, insecure bool, multiline *multiline.Rule) *HECHandler {
+	var allowed [][]byte
+	for _, token := range tokens {
+		if token = strings.TrimSpace(token); token != "" {
+			allowed = append(allowed, []byte(token))
+		}
+	}
+	return &HECHandler{producer: producer, tokens: allowed, insecure: insecure, multiline: multiline}
+}
+
+func (h *HECHandler) EventHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+
+	body, err := readBody(w, r, maxDecompressedBodySize)
+	if err != nil {
+		respondWithBodyError(w, err)
+		return
+	}
+
+	entries, err := splunk.ParseEvents(body)
+	h.publish(w, entries, err)
+}
+
+func (h *HECHandler) RawHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+
+	body, err := readBody(w, r, maxDecompressedBodySize)
+	if err != nil {
+		respondWithBodyError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	entries, err := splunk.ParseRaw(body, splunk.Metadata{
+		Host:       query.Get("host"),
+		Source:     query.Get("source"),
+		SourceType: query.Get("sourcetype"),
+		Index:      query.Get("index"),
+	}, h.multiline)
+	h.publish(w, entries, err)
+}
+
+func (h *HECHandler) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, splunk.Error{Text: "HEC is healthy", Code: 17})
+}
+
+func (h *HECHandler) publish(w http.ResponseWriter, entries []models.LogEntry, err error) {
+	var hecErr *splunk.Error
+	if errors.As(err, &hecErr) {
+		respondWithJSON(w, http.StatusBadRequest, hecErr)
+		return
+	}
+
+	for _, err := range h.producer.SendLogs(entries) {
+		if err != nil {
+			respondWithJSON(w, http.StatusServiceUnavailable, splunk.Error{Text: "Server is busy", Code: 9})
+			return
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, splunk.Error{Text: "Success", Code: 0})
+}
+
+// authorize checks the "Authorization: Splunk <token>" header.
+func (h *HECHandler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	scheme, token, _ := strings.Cut(header, " ")
+	if header == "" || !strings.EqualFold(scheme, "Splunk") || token == "" {
+		respondWithJSON(w, http.StatusUnauthorized, splunk.ErrTokenRequired)
+		return false
+	}
+	if !h.insecure && !h.validToken([]byte(strings.TrimSpace(token))) {
+		respondWithJSON(w, http.StatusForbidden, splunk.ErrInvalidToken)
+		return false
+	}
+	return true
+}
+
+// validToken compares against every configured token in constant time, so
+// the response time does not reveal how much of a token was guessed.
+func (h *HECHandler) validToken(token []byte) bool {
+	valid := 0
+	for _, allowed := range h.tokens {
+		valid |= subtle.ConstantTimeCompare(token, allowed)
+	}
+	return valid == 1
+}
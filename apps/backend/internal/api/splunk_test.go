@@ -0,0 +1,80 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aasheesh/logless/internal/splunk"
+)
+
+func TestHECHandlerAuthorization(t *testing.T) {
+	tests := []struct {
+		name     string
+		tokens   []string
+		insecure bool
+		header   string
+		code     int
+		hecCode  int
+	}{
+		{name: "valid token", tokens: []string{"t1", " t2 "}, header: "Splunk t2", code: http.StatusOK, hecCode: 0},
+		{name: "scheme is case insensitive", tokens: []string{"t1"}, header: "splunk t1", code: http.StatusOK, hecCode: 0},
+		{name: "missing header", tokens: []string{"t1"}, code: http.StatusUnauthorized, hecCode: 2},
+		{name: "wrong scheme", tokens: []string{"t1"}, header: "Bearer t1", code: http.StatusUnauthorized, hecCode: 2},
+		{name: "unknown token", tokens: []string{"t1"}, header: "Splunk t", code: http.StatusForbidden, hecCode: 4},
+		{name: "no tokens configured", header: "Splunk t1", code: http.StatusForbidden, hecCode: 4},
+		{name: "insecure accepts any token", insecure: true, header: "Splunk anything", code: http.StatusOK, hecCode: 0},
+		{name: "insecure still needs a token", insecure: true, code: http.StatusUnauthorized, hecCode: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHECHandler(newTestProducer(t, 100), tt.tokens, tt.insecure, nil)
+			req := httptest.NewRequest(http.MethodPost, "/services/collector/event", bytes.NewBufferString(`{"event":"hello"}`))
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			h.EventHandler(rec, req)
+
+			if rec.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.code, rec.Body)
+			}
+			var status splunk.Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil || status.Code != tt.hecCode {
+				t.Errorf("HEC code = %d (%v), want %d", status.Code, err, tt.hecCode)
+			}
+		})
+	}
+}
+
+func TestHECHandlerBadRequest(t *testing.T) {
+	h := NewHECHandler(newTestProducer(t, 100), []string{"t"}, false, nil)
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		code    int
+		hecCode int
+	}{
+		{name: "event without event field", handler: h.EventHandler, body: `{"host":"h"}`, code: http.StatusBadRequest, hecCode: 12},
+		{name: "empty raw body", handler: h.RawHandler, body: "", code: http.StatusBadRequest, hecCode: 5},
+		{name: "raw lines", handler: h.RawHandler, body: "a\nb\n", code: http.StatusOK, hecCode: 0},
+		{name: "queue full", handler: NewHECHandler(newTestProducer(t, 1), []string{"t"}, false, nil).RawHandler, body: "a\nb\n", code: http.StatusServiceUnavailable, hecCode: 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/services/collector", bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Splunk t")
+			rec := httptest.NewRecorder()
+			tt.handler(rec, req)
+
+			var status splunk.Error
+			json.Unmarshal(rec.Body.Bytes(), &status)
+			if rec.Code != tt.code || status.Code != tt.hecCode {
+				t.Errorf("got %d with HEC code %d, want %d with %d", rec.Code, status.Code, tt.code, tt.hecCode)
+			}
+		})
+	}
+}
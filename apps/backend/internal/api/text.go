@@ -0,0 +1,48 @@
+package api
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/textlog"
+)
+
+// isTextContentType reports whether a body should be read as plain-text or
+// logfmt lines rather than a JSON entry.
+func isTextContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "text/plain", "text/logfmt", "application/logfmt":
+		return true
+	}
+	return false
+}
+
+// textLogHandler stores one entry per line, with key=value pairs in Context.
+func (h *LogHandler) textLogHandler(w http.ResponseWriter, body []byte) {
+	entries, err := textlog.ParseLines(body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "failed to read text payload")
+		return
+	}
+	if len(entries) == 0 {
+		respondWithError(w, http.StatusBadRequest, "no log lines in request")
+		return
+	}
+	if len(entries) > maxBatchEntries {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "too many log lines in request")
+		return
+	}
+
+	response := models.BatchIngestResponse{Results: make([]models.IngestResult, len(entries))}
+	indexes := make([]int, len(entries))
+	for i := range entries {
+		response.Results[i].Index = i
+		indexes[i] = i
+	}
+	h.publishBatch(w, response, entries, indexes)
+}
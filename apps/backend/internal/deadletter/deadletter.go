@@ -0,0 +1,102 @@
+// Package deadletter moves messages the consumer cannot store to a separate
+// Kafka topic, and lets an operator inspect them and send them back.
+//
+// The consumer runs its redact processors over a message before it is
+// dead-lettered. Without a pipeline, letters hold the payload as received,
+// secrets included, which is why the admin API requires a token.
+package deadletter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+const DefaultTopic = "logs-dlq"
+
+// Headers set on every dead letter. Attempts is also set on redriven
+// messages so the count keeps growing if they fail again.
+const (
+	HeaderError             = "logless-error"
+	HeaderOriginalTopic     = "logless-original-topic"
+	HeaderOriginalPartition = "logless-original-partition"
+	HeaderOriginalOffset    = "logless-original-offset"
+	HeaderAttempts          = "logless-attempts"
+	HeaderFailedAt          = "logless-failed-at"
+	HeaderRedrivenFrom      = "logless-redriven-from"
+)
+
+// deliveryTimeout bounds how long a synchronous produce waits for the broker.
+const deliveryTimeout = 30 * time.Second
+
+// Writer sends failed messages to the dead-letter topic.
+type Writer struct {
+	producer *kafka.Producer
+	topic    string
+}
+
+func NewWriter(producer *kafka.Producer, topic string) *Writer {
+	return &Writer{producer: producer, topic: topic}
+}
+
+// Send writes value to the dead-letter topic and waits until the broker has
+// it, so the caller can commit the original offset afterwards.
+func (w *Writer) Send(value []byte, origin kafka.TopicPartition, attempts int, cause error) error {
+	var topic string
+	if origin.Topic != nil {
+		topic = *origin.Topic
+	}
+
+	_, err := produceSync(w.producer, &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &w.topic, Partition: kafka.PartitionAny},
+		Value:          value,
+		Headers: []kafka.Header{
+			{Key: HeaderError, Value: []byte(cause.Error())},
+			{Key: HeaderOriginalTopic, Value: []byte(topic)},
+			{Key: HeaderOriginalPartition, Value: []byte(strconv.Itoa(int(origin.Partition)))},
+			{Key: HeaderOriginalOffset, Value: []byte(strconv.FormatInt(int64(origin.Offset), 10))},
+			{Key: HeaderAttempts, Value: []byte(strconv.Itoa(attempts))},
+			{Key: HeaderFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339Nano))},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	return nil
+}
+
+// Attempts returns the attempt count carried by a message, 0 when it has
+// never failed before.
+func Attempts(headers []kafka.Header) int {
+	for _, h := range headers {
+		if h.Key == HeaderAttempts {
+			n, _ := strconv.Atoi(string(h.Value))
+			return n
+		}
+	}
+	return 0
+}
+
+func produceSync(producer *kafka.Producer, msg *kafka.Message) (kafka.TopicPartition, error) {
+	delivery := make(chan kafka.Event, 1)
+	if err := producer.Produce(msg, delivery); err != nil {
+		return kafka.TopicPartition{}, err
+	}
+
+	select {
+	case ev := <-delivery:
+		m, ok := ev.(*kafka.Message)
+		if !ok {
+			return kafka.TopicPartition{}, fmt.Errorf("unexpected delivery event %v", ev)
+		}
+		if m.TopicPartition.Error != nil {
+			return kafka.TopicPartition{}, m.TopicPartition.Error
+		}
+		return m.TopicPartition, nil
+	case <-time.After(deliveryTimeout):
+		return kafka.TopicPartition{}, errors.New("timed out waiting for delivery")
+	}
+}
@@ -0,0 +1,59 @@
+package deadletter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+func TestAttempts(t *testing.T) {
+	tests := []struct {
+		headers []kafka.Header
+		want    int
+	}{
+		{headers: nil, want: 0},
+		{headers: []kafka.Header{{Key: "other", Value: []byte("9")}}, want: 0},
+		{headers: []kafka.Header{{Key: HeaderAttempts, Value: []byte("3")}}, want: 3},
+		{headers: []kafka.Header{{Key: HeaderAttempts, Value: []byte("many")}}, want: 0},
+	}
+	for _, tt := range tests {
+		if got := Attempts(tt.headers); got != tt.want {
+			t.Errorf("Attempts(%v) = %d, want %d", tt.headers, got, tt.want)
+		}
+	}
+}
+
+func TestNewLetter(t *testing.T) {
+	topic := DefaultTopic
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 2, Offset: 40},
+		Timestamp:      ts,
+		Value:          []byte{0xff, 'x'},
+		Headers: []kafka.Header{
+			{Key: HeaderError, Value: []byte("bad json")},
+			{Key: HeaderOriginalTopic, Value: []byte("logs")},
+			{Key: HeaderOriginalPartition, Value: []byte("1")},
+			{Key: HeaderOriginalOffset, Value: []byte("99")},
+			{Key: HeaderAttempts, Value: []byte("2")},
+			{Key: HeaderFailedAt, Value: []byte("2024-05-01T10:00:00Z")},
+		},
+	}
+	r := NewReader("", topic, "logs", nil)
+	r.redriven[letterKey(topic, 2, 40)] = "logs/0/7"
+
+	want := Letter{
+		Partition: 2, Offset: 40, Timestamp: ts, Error: "bad json",
+		OriginalTopic: "logs", OriginalPartition: 1, OriginalOffset: 99,
+		Attempts: 2, FailedAt: "2024-05-01T10:00:00Z", Size: 2, RedrivenTo: "logs/0/7",
+	}
+	if got := r.newLetter(msg, false); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if got := r.newLetter(msg, true); string(got.Value) != string(msg.Value) {
+		t.Errorf("inspected letter value = %q, want %q", got.Value, msg.Value)
+	}
+}
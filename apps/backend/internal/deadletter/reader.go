@@ -0,0 +1,280 @@
+package deadletter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+var (
+	ErrNotFound        = errors.New("dead letter not found")
+	ErrAlreadyRedriven = errors.New("dead letter was already redriven")
+)
+
+// readTimeout bounds how long one admin request spends reading the topic.
+const readTimeout = 5 * time.Second
+
+// Letter describes one dead-lettered message. Value is only filled in when a
+// single letter is inspected; it is encoded as base64 in JSON because the
+// messages that end up here are often not valid UTF-8.
+type Letter struct {
+	Partition         int32     `json:"partition"`
+	Offset            int64     `json:"offset"`
+	Timestamp         time.Time `json:"timestamp"`
+	Error             string    `json:"error"`
+	OriginalTopic     string    `json:"original_topic"`
+	OriginalPartition int32     `json:"original_partition"`
+	OriginalOffset    int64     `json:"original_offset"`
+	Attempts          int       `json:"attempts"`
+	FailedAt          string    `json:"failed_at,omitempty"`
+	Size              int       `json:"size"`
+	Value             []byte    `json:"value,omitempty"`
+	// RedrivenTo is where the letter was redriven to by this server, as
+	// topic/partition/offset.
+	RedrivenTo string `json:"redriven_to,omitempty"`
+}
+
+// Reader reads the dead-letter topic without a consumer group offset, so
+// browsing it never changes what anyone else sees.
+type Reader struct {
+	brokers  string
+	topic    string
+	target   string
+	producer *kafka.Producer
+
+	// redriven records the letters redriven since startup, so a retried
+	// request does not enqueue the same letter twice. An empty destination
+	// means the redrive is still in flight.
+	mu       sync.Mutex
+	redriven map[string]string
+}
+
+// NewReader reads topic from brokers and redrives letters to target through
+// producer.
+func NewReader(brokers, topic, target string, producer *kafka.Producer) *Reader {
+	return &Reader{brokers: brokers, topic: topic, target: target, producer: producer, redriven: make(map[string]string)}
+}
+
+func (r *Reader) newConsumer() (*kafka.Consumer, error) {
+	c, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":  r.brokers,
+		"group.id":           "logless-dead-letter-admin",
+		"enable.auto.commit": false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dead letter consumer: %w", err)
+	}
+	return c, nil
+}
+
+// List returns up to limit letters from each selected partition, starting at
+// from, or at the oldest retained letter when from is negative. A negative
+// partition selects every partition.
+func (r *Reader) List(partition int32, from int64, limit int) ([]Letter, error) {
+	c, err := r.newConsumer()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	metadata, err := c.GetMetadata(&r.topic, false, int(readTimeout/time.Millisecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter topic metadata: %w", err)
+	}
+	topic, ok := metadata.Topics[r.topic]
+	if !ok || topic.Error.Code() == kafka.ErrUnknownTopicOrPart {
+		return []Letter{}, nil
+	}
+
+	var assignment []kafka.TopicPartition
+	ends := make(map[int32]int64)
+	counts := make(map[int32]int)
+	for _, p := range topic.Partitions {
+		if partition >= 0 && p.ID != partition {
+			continue
+		}
+		low, high, err := c.QueryWatermarkOffsets(r.topic, p.ID, int(readTimeout/time.Millisecond))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get dead letter offsets: %w", err)
+		}
+		start := low
+		if from >= 0 {
+			start = max(from, low)
+		}
+		if start >= high {
+			continue
+		}
+		assignment = append(assignment, kafka.TopicPartition{Topic: &r.topic, Partition: p.ID, Offset: kafka.Offset(start)})
+		ends[p.ID] = high
+	}
+
+	letters := []Letter{}
+	if len(assignment) == 0 {
+		return letters, nil
+	}
+	if err := c.Assign(assignment); err != nil {
+		return nil, fmt.Errorf("failed to assign dead letter partitions: %w", err)
+	}
+
+	deadline := time.Now().Add(readTimeout)
+	for len(ends) > 0 && time.Now().Before(deadline) {
+		msg, err := c.ReadMessage(time.Until(deadline))
+		if err != nil {
+			if kerr, ok := err.(kafka.Error); ok && kerr.Code() == kafka.ErrTimedOut {
+				break
+			}
+			return nil, fmt.Errorf("failed to read dead letters: %w", err)
+		}
+
+		p := msg.TopicPartition.Partition
+		if _, reading := ends[p]; !reading {
+			continue
+		}
+		letters = append(letters, r.newLetter(msg, false))
+		counts[p]++
+		if int64(msg.TopicPartition.Offset)+1 >= ends[p] || counts[p] >= limit {
+			delete(ends, p)
+		}
+	}
+
+	sort.Slice(letters, func(i, j int) bool {
+		if letters[i].Partition != letters[j].Partition {
+			return letters[i].Partition < letters[j].Partition
+		}
+		return letters[i].Offset < letters[j].Offset
+	})
+	return letters, nil
+}
+
+// Get returns one letter with its value.
+func (r *Reader) Get(partition int32, offset int64) (Letter, error) {
+	msg, err := r.read(partition, offset)
+	if err != nil {
+		return Letter{}, err
+	}
+	return r.newLetter(msg, true), nil
+}
+
+// Redrive produces a letter back onto the target topic and returns where it
+// landed. A non-nil value replaces the original, e.g. after fixing it by hand.
+// A letter already redriven returns ErrAlreadyRedriven unless force is set.
+func (r *Reader) Redrive(partition int32, offset int64, value []byte, force bool) (kafka.TopicPartition, error) {
+	key := letterKey(r.topic, partition, offset)
+	r.mu.Lock()
+	if _, done := r.redriven[key]; done && !force {
+		r.mu.Unlock()
+		return kafka.TopicPartition{}, ErrAlreadyRedriven
+	}
+	previous, hadPrevious := r.redriven[key]
+	r.redriven[key] = ""
+	r.mu.Unlock()
+
+	tp, err := r.redrive(partition, offset, value)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case err == nil:
+		r.redriven[key] = letterKey(*tp.Topic, tp.Partition, int64(tp.Offset))
+	case hadPrevious:
+		r.redriven[key] = previous
+	default:
+		delete(r.redriven, key)
+	}
+	return tp, err
+}
+
+func (r *Reader) redrive(partition int32, offset int64, value []byte) (kafka.TopicPartition, error) {
+	msg, err := r.read(partition, offset)
+	if err != nil {
+		return kafka.TopicPartition{}, err
+	}
+	if value == nil {
+		value = msg.Value
+	}
+
+	return produceSync(r.producer, &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &r.target, Partition: kafka.PartitionAny},
+		Value:          value,
+		Headers: []kafka.Header{
+			{Key: HeaderRedrivenFrom, Value: []byte(letterKey(r.topic, partition, offset))},
+			{Key: HeaderAttempts, Value: []byte(strconv.Itoa(Attempts(msg.Headers)))},
+		},
+	})
+}
+
+func letterKey(topic string, partition int32, offset int64) string {
+	return fmt.Sprintf("%s/%d/%d", topic, partition, offset)
+}
+
+func (r *Reader) read(partition int32, offset int64) (*kafka.Message, error) {
+	c, err := r.newConsumer()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	low, high, err := c.QueryWatermarkOffsets(r.topic, partition, int(readTimeout/time.Millisecond))
+	if err != nil {
+		if kerr, ok := err.(kafka.Error); ok && (kerr.Code() == kafka.ErrUnknownTopicOrPart || kerr.Code() == kafka.ErrUnknownPartition) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get dead letter offsets: %w", err)
+	}
+	if offset < low || offset >= high {
+		return nil, ErrNotFound
+	}
+
+	if err := c.Assign([]kafka.TopicPartition{{Topic: &r.topic, Partition: partition, Offset: kafka.Offset(offset)}}); err != nil {
+		return nil, fmt.Errorf("failed to assign dead letter partition: %w", err)
+	}
+	msg, err := c.ReadMessage(readTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letter: %w", err)
+	}
+	if int64(msg.TopicPartition.Offset) != offset {
+		// The offset was a transaction marker or has been compacted away.
+		return nil, ErrNotFound
+	}
+	return msg, nil
+}
+
+func (r *Reader) newLetter(msg *kafka.Message, withValue bool) Letter {
+	letter := Letter{
+		Partition: msg.TopicPartition.Partition,
+		Offset:    int64(msg.TopicPartition.Offset),
+		Timestamp: msg.Timestamp,
+		Size:      len(msg.Value),
+	}
+	for _, h := range msg.Headers {
+		value := string(h.Value)
+		switch h.Key {
+		case HeaderError:
+			letter.Error = value
+		case HeaderOriginalTopic:
+			letter.OriginalTopic = value
+		case HeaderOriginalPartition:
+			n, _ := strconv.Atoi(value)
+			letter.OriginalPartition = int32(n)
+		case HeaderOriginalOffset:
+			letter.OriginalOffset, _ = strconv.ParseInt(value, 10, 64)
+		case HeaderAttempts:
+			letter.Attempts, _ = strconv.Atoi(value)
+		case HeaderFailedAt:
+			letter.FailedAt = value
+		}
+	}
+	if withValue {
+		letter.Value = msg.Value
+	}
+
+	r.mu.Lock()
+	letter.RedrivenTo = r.redriven[letterKey(r.topic, letter.Partition, letter.Offset)]
+	r.mu.Unlock()
+	return letter
+}
@@ -0,0 +1,190 @@
+// Package dedup collapses bursts of identical entries, such as the same
+// error from a crash loop, into one representative that records how often it
+// repeated.
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/severity"
+	"github.com/aasheesh/logless/internal/utils"
+)
+
+type Config struct {
+	// Window is how long a burst stays open after its latest repeat.
+	Window time.Duration
+	// MaxSpan emits a burst that has been open this long even while it keeps
+	// repeating, so a crash loop produces one entry per MaxSpan.
+	MaxSpan time.Duration
+	// Keys are context keys that also distinguish entries, e.g. "user_id".
+	Keys []string
+	// MaxGroups bounds memory; the oldest burst is emitted when it is reached.
+	MaxGroups int
+}
+
+// Burst is an emitted representative and the ref passed with the first
+// entry of its burst.
+type Burst[R any] struct {
+	Entry models.LogEntry
+	Ref   R
+}
+
+type group[R any] struct {
+	entry     models.LogEntry
+	ref       R
+	count     int
+	firstSeen time.Time
+	lastSeen  time.Time
+	opened    time.Time
+	updated   time.Time
+}
+
+// Deduper holds one representative per fingerprint until its burst ends.
+// Each representative carries a ref chosen by the caller, such as the Kafka
+// offset it was read from. It is not safe for concurrent use.
+type Deduper[R any] struct {
+	config Config
+	groups map[string]*group[R]
+	order  []string
+}
+
+func New[R any](config Config) *Deduper[R] {
+	if config.MaxSpan <= 0 {
+		config.MaxSpan = time.Minute
+	}
+	if config.MaxGroups <= 0 {
+		config.MaxGroups = 10000
+	}
+	return &Deduper[R]{config: config, groups: make(map[string]*group[R])}
+}
+
+// Add records an entry and reports whether it is held as the representative
+// of a new burst; otherwise it was folded into a held one. It also returns a
+// representative that had to be emitted early to stay within MaxGroups.
+func (d *Deduper[R]) Add(entry models.LogEntry, ref R, now time.Time) ([]Burst[R], bool) {
+	entry.PromoteFields()
+	key := d.fingerprint(entry)
+
+	seen := entry.Timestamp
+	if seen.IsZero() {
+		seen = now
+	}
+
+	if g, ok := d.groups[key]; ok {
+		g.count++
+		if seen.Before(g.firstSeen) {
+			g.firstSeen = seen
+		}
+		if seen.After(g.lastSeen) {
+			g.lastSeen = seen
+		}
+		g.updated = now
+		return nil, false
+	}
+
+	var evicted []Burst[R]
+	if len(d.groups) >= d.config.MaxGroups {
+		evicted = append(evicted, d.emit(d.order[0]))
+	}
+	d.groups[key] = &group[R]{entry: entry, ref: ref, count: 1, firstSeen: seen, lastSeen: seen, opened: now, updated: now}
+	d.order = append(d.order, key)
+	return evicted, true
+}
+
+// Expired returns the representatives of bursts that ended by now.
+func (d *Deduper[R]) Expired(now time.Time) []Burst[R] {
+	var entries []Burst[R]
+	for _, key := range append([]string(nil), d.order...) {
+		g := d.groups[key]
+		if now.Sub(g.updated) >= d.config.Window || now.Sub(g.opened) >= d.config.MaxSpan {
+			entries = append(entries, d.emit(key))
+		}
+	}
+	return entries
+}
+
+// Flush returns every held representative, e.g. on shutdown.
+func (d *Deduper[R]) Flush() []Burst[R] {
+	var entries []Burst[R]
+	for len(d.order) > 0 {
+		entries = append(entries, d.emit(d.order[0]))
+	}
+	return entries
+}
+
+// Len is the number of bursts currently held.
+func (d *Deduper[R]) Len() int {
+	return len(d.groups)
+}
+
+func (d *Deduper[R]) emit(key string) Burst[R] {
+	g := d.groups[key]
+	delete(d.groups, key)
+	for i, k := range d.order {
+		if k == key {
+			d.order = append(d.order[:i], d.order[i+1:]...)
+			break
+		}
+	}
+
+	entry := g.entry
+	if g.count > 1 {
+		entry.RepeatCount = g.count
+		entry.FirstSeen = g.firstSeen
+		entry.LastSeen = g.lastSeen
+	}
+	return Burst[R]{Entry: entry, Ref: g.ref}
+}
+
+// fingerprint identifies entries that are the same event: service, level,
+// the message with variable parts such as numbers and IDs masked, and the
+// configured context keys.
+func (d *Deduper[R]) fingerprint(entry models.LogEntry) string {
+	level := strings.ToLower(entry.Level)
+	if s, ok := severity.Parse(level); ok {
+		level = s.String()
+	}
+
+	h := sha256.New()
+	for _, part := range []string{entry.Service, level, normalizeMessage(entry.Message)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	for _, key := range d.config.Keys {
+		if value, ok := utils.LookupPath(entry.Context, key); ok {
+			h.Write([]byte(utils.JSONString(value)))
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+var (
+	uuidPattern   = regexp.MustCompile(`[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}`)
+	ipPattern     = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}(?::\d+)?\b`)
+	hexPattern    = regexp.MustCompile(`\b(?:0x)?[0-9A-Fa-f]{6,}\b`)
+	numberPattern = regexp.MustCompile(`\d+(?:\.\d+)?`)
+	spacePattern  = regexp.MustCompile(`\s+`)
+)
+
+// normalizeMessage masks the parts of a message that usually differ between
+// repeats of the same event, so "timeout after 31ms (req 8f3a21c0)" and
+// "timeout after 29ms (req 1c0b9e4d)" compare equal.
+func normalizeMessage(message string) string {
+	message = uuidPattern.ReplaceAllString(message, "<uuid>")
+	message = ipPattern.ReplaceAllString(message, "<ip>")
+	message = hexPattern.ReplaceAllStringFunc(message, func(s string) string {
+		// Words such as "facade" are hex too; IDs almost always have a digit.
+		if strings.ContainsAny(s, "0123456789") {
+			return "<hex>"
+		}
+		return s
+	})
+	message = numberPattern.ReplaceAllString(message, "<num>")
+	return strings.TrimSpace(spacePattern.ReplaceAllString(message, " "))
+}
@@ -0,0 +1,170 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+)
+
+func TestNormalizeMessage(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"timeout after 31ms (req 8f3a21c0)", "timeout after 29ms (req 1c0b9e4d)", true},
+		{"user 550e8400-e29b-41d4-a716-446655440000 not found", "user 6ba7b810-9dad-11d1-80b4-00c04fd430c8 not found", true},
+		{"dial 10.0.0.1:5432 refused", "dial 10.0.0.2:6543 refused", true},
+		{"took 1.5s", "took 12s", true},
+		{"a   b\tc ", "a b c", true},
+		{"facade failed", "decade failed", false},
+		{"timeout", "refused", false},
+	}
+	for _, tt := range tests {
+		a, b := normalizeMessage(tt.a), normalizeMessage(tt.b)
+		if (a == b) != tt.equal {
+			t.Errorf("normalizeMessage(%q) = %q, normalizeMessage(%q) = %q, equal want %v", tt.a, a, tt.b, b, tt.equal)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	d := New[int](Config{Window: time.Second, Keys: []string{"user_id"}})
+	base := models.LogEntry{Service: "api", Level: "ERROR", Message: "failed after 3 tries", Context: models.Context{"user_id": 1}}
+
+	tests := []struct {
+		name  string
+		other models.LogEntry
+		same  bool
+	}{
+		{name: "level alias", other: models.LogEntry{Service: "api", Level: "err", Message: "failed after 4 tries", Context: models.Context{"user_id": 1}}, same: true},
+		{name: "other service", other: models.LogEntry{Service: "web", Level: "error", Message: "failed after 3 tries", Context: models.Context{"user_id": 1}}},
+		{name: "other level", other: models.LogEntry{Service: "api", Level: "warn", Message: "failed after 3 tries", Context: models.Context{"user_id": 1}}},
+		{name: "other key value", other: models.LogEntry{Service: "api", Level: "error", Message: "failed after 3 tries", Context: models.Context{"user_id": 2}}},
+		{name: "missing key", other: models.LogEntry{Service: "api", Level: "error", Message: "failed after 3 tries"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := d.fingerprint(base) == d.fingerprint(tt.other); same != tt.same {
+				t.Errorf("same fingerprint = %v, want %v", same, tt.same)
+			}
+		})
+	}
+}
+
+func TestDeduperWindows(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	entry := models.LogEntry{Service: "api", Level: "error", Message: "boom 1"}
+
+	type add struct {
+		at  time.Duration
+		ref int
+	}
+	tests := []struct {
+		name    string
+		config  Config
+		adds    []add
+		expire  time.Duration
+		want    []int // refs of the emitted bursts
+		counts  []int
+		holding int
+	}{
+		{
+			name:    "burst still open",
+			config:  Config{Window: 10 * time.Second},
+			adds:    []add{{0, 1}, {time.Second, 2}},
+			expire:  5 * time.Second,
+			holding: 1,
+		},
+		{
+			name:   "burst closed after window",
+			config: Config{Window: 10 * time.Second},
+			adds:   []add{{0, 1}, {time.Second, 2}, {2 * time.Second, 3}},
+			expire: 12 * time.Second,
+			want:   []int{1},
+			counts: []int{3},
+		},
+		{
+			name:   "single entry has no repeat count",
+			config: Config{Window: time.Second},
+			adds:   []add{{0, 7}},
+			expire: time.Second,
+			want:   []int{7},
+			counts: []int{0},
+		},
+		{
+			name:   "max span ends a burst that keeps repeating",
+			config: Config{Window: 10 * time.Second, MaxSpan: 30 * time.Second},
+			adds:   []add{{0, 1}, {9 * time.Second, 2}, {18 * time.Second, 3}, {27 * time.Second, 4}},
+			expire: 30 * time.Second,
+			want:   []int{1},
+			counts: []int{4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New[int](tt.config)
+			for _, a := range tt.adds {
+				d.Add(entry, a.ref, at(a.at))
+			}
+			bursts := d.Expired(at(tt.expire))
+			if len(bursts) != len(tt.want) {
+				t.Fatalf("emitted %d bursts, want %d", len(bursts), len(tt.want))
+			}
+			for i, b := range bursts {
+				if b.Ref != tt.want[i] || b.Entry.RepeatCount != tt.counts[i] {
+					t.Errorf("burst %d: ref %d count %d, want ref %d count %d", i, b.Ref, b.Entry.RepeatCount, tt.want[i], tt.counts[i])
+				}
+			}
+			if d.Len() != tt.holding {
+				t.Errorf("holding %d bursts, want %d", d.Len(), tt.holding)
+			}
+		})
+	}
+}
+
+func TestDeduperFirstAndLastSeen(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := New[int](Config{Window: time.Minute})
+
+	for i, offset := range []time.Duration{5 * time.Second, 0, 9 * time.Second, 2 * time.Second} {
+		entry := models.LogEntry{Message: "retry", Timestamp: start.Add(offset)}
+		if _, isNew := d.Add(entry, i, start); isNew != (i == 0) {
+			t.Fatalf("add %d: new = %v", i, isNew)
+		}
+	}
+
+	bursts := d.Flush()
+	if len(bursts) != 1 {
+		t.Fatalf("flushed %d bursts, want 1", len(bursts))
+	}
+	got := bursts[0].Entry
+	if got.RepeatCount != 4 || !got.FirstSeen.Equal(start) || !got.LastSeen.Equal(start.Add(9*time.Second)) {
+		t.Errorf("count %d first %v last %v", got.RepeatCount, got.FirstSeen, got.LastSeen)
+	}
+	if !got.Timestamp.Equal(start.Add(5 * time.Second)) {
+		t.Errorf("representative timestamp = %v, want the first entry's", got.Timestamp)
+	}
+	if d.Len() != 0 {
+		t.Errorf("holding %d bursts after Flush", d.Len())
+	}
+}
+
+func TestDeduperMaxGroups(t *testing.T) {
+	now := time.Now()
+	d := New[int](Config{Window: time.Minute, MaxGroups: 2})
+
+	messages := []string{"first", "second", "first", "third"}
+	var evicted []Burst[int]
+	for i, message := range messages {
+		out, _ := d.Add(models.LogEntry{Message: message}, i, now)
+		evicted = append(evicted, out...)
+	}
+	if len(evicted) != 1 || evicted[0].Entry.Message != "first" || evicted[0].Entry.RepeatCount != 2 {
+		t.Fatalf("evicted %+v, want the oldest burst with two repeats", evicted)
+	}
+	if d.Len() != 2 {
+		t.Errorf("holding %d bursts, want 2", d.Len())
+	}
+}
@@ -0,0 +1,316 @@
+package dockerdriver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aasheesh/logless/pkg/logless"
+)
+
+// maxPartialSize caps how much of a split line is buffered before it is sent
+// as is.
+const maxPartialSize = 1 << 20
+
+// Info describes the container being logged, mirroring logger.Info in moby.
+type Info struct {
+	Config              map[string]string
+	ContainerID         string
+	ContainerName       string
+	ContainerEntrypoint string
+	ContainerArgs       []string
+	ContainerImageID    string
+	ContainerImageName  string
+	ContainerCreated    time.Time
+	ContainerEnv        []string
+	ContainerLabels     map[string]string
+	LogPath             string
+	DaemonName          string
+}
+
+// ReadConfig mirrors logger.ReadConfig in moby. A negative Tail returns every
+// buffered line.
+type ReadConfig struct {
+	Since  time.Time
+	Until  time.Time
+	Tail   int
+	Follow bool
+}
+
+// Options accepted through --log-opt.
+const (
+	optLabels = "labels"
+	optEnv    = "env"
+	optTag    = "tag"
+)
+
+type Config struct {
+	// HistoryLines is the number of recent lines kept per container to
+	// answer `docker logs`.
+	HistoryLines int
+	// HistoryContainers bounds how many stopped containers keep their history.
+	HistoryContainers int
+}
+
+// Driver keeps one reader per FIFO handed over by the Docker daemon.
+type Driver struct {
+	config Config
+	client *logless.Client
+
+	mu      sync.Mutex
+	streams map[string]*stream
+	history map[string]*history
+	stopped []string
+}
+
+type stream struct {
+	info    Info
+	fifo    io.Closer
+	context map[string]string
+	history *history
+}
+
+func NewDriver(config Config, client *logless.Client) *Driver {
+	if config.HistoryLines <= 0 {
+		config.HistoryLines = 1000
+	}
+	if config.HistoryContainers <= 0 {
+		config.HistoryContainers = 100
+	}
+	return &Driver{
+		config:  config,
+		client:  client,
+		streams: make(map[string]*stream),
+		history: make(map[string]*history),
+	}
+}
+
+// StartLogging begins reading the FIFO at file. Opening a FIFO blocks until
+// the daemon opens the write side, so it happens on the reader goroutine.
+func (d *Driver) StartLogging(file string, info Info) error {
+	for key := range info.Config {
+		switch key {
+		case optLabels, optEnv, optTag:
+		default:
+			return fmt.Errorf("unknown log opt %q for logless log driver", key)
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.streams[file]; ok {
+		return fmt.Errorf("logger for %s already exists", file)
+	}
+
+	h, ok := d.history[info.ContainerID]
+	if !ok {
+		h = newHistory(d.config.HistoryLines)
+		d.history[info.ContainerID] = h
+	}
+	h.restart()
+
+	s := &stream{info: info, context: containerContext(info), history: h}
+	d.streams[file] = s
+
+	go d.consume(file, s)
+	return nil
+}
+
+// StopLogging releases the FIFO. The daemon closes the write side first, so
+// the reader normally sees EOF on its own.
+func (d *Driver) StopLogging(file string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s, ok := d.streams[file]
+	if !ok {
+		return nil
+	}
+	delete(d.streams, file)
+	if s.fifo != nil {
+		s.fifo.Close()
+	}
+	s.history.stop()
+
+	d.stopped = append(d.stopped, s.info.ContainerID)
+	for len(d.stopped) > d.config.HistoryContainers {
+		id := d.stopped[0]
+		d.stopped = d.stopped[1:]
+		if h, ok := d.history[id]; ok && h.isStopped() {
+			delete(d.history, id)
+		}
+	}
+	return nil
+}
+
+// ReadLogs returns a reader of framed entries from the container's recent
+// history, as `docker logs` expects.
+func (d *Driver) ReadLogs(config ReadConfig, info Info) (io.ReadCloser, error) {
+	d.mu.Lock()
+	h, ok := d.history[info.ContainerID]
+	d.mu.Unlock()
+	if !ok {
+		return nil, errors.New("no logs buffered for this container, query LogLess instead")
+	}
+
+	r, w := io.Pipe()
+	reader := &logReader{PipeReader: r, done: make(chan struct{})}
+	go func() {
+		w.CloseWithError(h.read(config, w, reader.done))
+	}()
+	return reader, nil
+}
+
+// logReader stops the follower writing to it as soon as it is closed, rather
+// than when the next line arrives.
+type logReader struct {
+	*io.PipeReader
+	once sync.Once
+	done chan struct{}
+}
+
+func (r *logReader) Close() error {
+	r.once.Do(func() { close(r.done) })
+	return r.PipeReader.Close()
+}
+
+func (d *Driver) consume(file string, s *stream) {
+	f, err := os.OpenFile(file, os.O_RDONLY, 0)
+	if err != nil {
+		log.Printf("Failed to open log FIFO %s: %v", file, err)
+		return
+	}
+	defer f.Close()
+
+	d.mu.Lock()
+	if d.streams[file] != s {
+		// Stopped while the FIFO was being opened.
+		d.mu.Unlock()
+		return
+	}
+	s.fifo = f
+	d.mu.Unlock()
+
+	partials := make(map[string]*Entry)
+	for {
+		entry, err := ReadEntry(f)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, os.ErrClosed) {
+				log.Printf("Failed to read log FIFO %s: %v", file, err)
+			}
+			break
+		}
+
+		if entry, ok := assemble(partials, entry); ok {
+			d.send(s, entry)
+		}
+	}
+
+	for _, entry := range partials {
+		d.send(s, *entry)
+	}
+}
+
+// assemble joins the pieces of lines Docker split at 16KiB. Newer daemons
+// mark pieces with PartialLogEntryMetadata and set Partial on every piece,
+// the last included, so only PartialLast ends those. Older daemons send no
+// metadata and set Partial on every piece but the last.
+func assemble(partials map[string]*Entry, entry Entry) (Entry, bool) {
+	key, partial := entry.PartialID, !entry.PartialLast
+	if key == "" {
+		key, partial = entry.Source, entry.Partial
+	}
+
+	pending, ok := partials[key]
+	if !ok {
+		if !partial {
+			return entry, true
+		}
+		pending = &Entry{Source: entry.Source, TimeNano: entry.TimeNano}
+		partials[key] = pending
+	}
+	pending.Line = append(pending.Line, entry.Line...)
+
+	if partial && len(pending.Line) < maxPartialSize {
+		return Entry{}, false
+	}
+	delete(partials, key)
+	return *pending, true
+}
+
+func (d *Driver) send(s *stream, entry Entry) {
+	if len(entry.Line) == 0 {
+		return
+	}
+	s.history.append(entry)
+
+	context := make(map[string]any, len(s.context)+1)
+	for key, value := range s.context {
+		context[key] = value
+	}
+	if entry.Source != "" {
+		context["stream"] = entry.Source
+	}
+
+	var timestamp time.Time
+	if entry.TimeNano != 0 {
+		timestamp = time.Unix(0, entry.TimeNano)
+	}
+	if err := d.client.Send(logless.Entry{
+		Message:   string(entry.Line),
+		Timestamp: timestamp,
+		Context:   context,
+	}); err != nil {
+		log.Printf("Failed to queue log line for %s: %v", s.info.ContainerID, err)
+	}
+}
+
+// containerContext builds the Context fields shared by every line of a
+// container, including the labels and environment variables selected through
+// the labels and env log opts.
+func containerContext(info Info) map[string]string {
+	context := map[string]string{
+		"container_id":   info.ContainerID,
+		"container_name": strings.TrimPrefix(info.ContainerName, "/"),
+		"image":          info.ContainerImageName,
+	}
+	if info.DaemonName != "" {
+		context["daemon"] = info.DaemonName
+	}
+	if tag := info.Config[optTag]; tag != "" {
+		context["tag"] = tag
+	}
+
+	for _, key := range splitOption(info.Config[optLabels]) {
+		if value, ok := info.ContainerLabels[key]; ok {
+			context["label."+key] = value
+		}
+	}
+
+	wanted := splitOption(info.Config[optEnv])
+	for _, variable := range info.ContainerEnv {
+		key, value, _ := strings.Cut(variable, "=")
+		for _, w := range wanted {
+			if key == w {
+				context["env."+key] = value
+			}
+		}
+	}
+	return context
+}
+
+func splitOption(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,150 @@
+//go:build unix
+
+package dockerdriver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aasheesh/logless/pkg/logless"
+)
+
+// startFakeFIFO starts logging a container from a FIFO in a temporary
+// directory and returns the write side, as the Docker daemon would hold it.
+func startFakeFIFO(t *testing.T, d *Driver, info Info) (string, *os.File) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fifo")
+	if err := syscall.Mkfifo(path, 0o600); err != nil {
+		t.Fatalf("mkfifo: %v", err)
+	}
+	if err := d.StartLogging(path, info); err != nil {
+		t.Fatalf("StartLogging: %v", err)
+	}
+	// Blocks until the driver opens the read side.
+	w, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("open fifo: %v", err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return path, w
+}
+
+func newTestDriver(t *testing.T) *Driver {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := logless.NewClient(logless.Config{Endpoint: server.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close(t.Context()) })
+	return NewDriver(Config{HistoryLines: 10}, client)
+}
+
+func writeEntries(t *testing.T, w *os.File, entries ...Entry) {
+	t.Helper()
+	for _, entry := range entries {
+		if err := WriteEntry(w, entry); err != nil {
+			t.Fatalf("WriteEntry: %v", err)
+		}
+	}
+}
+
+// waitForLines polls ReadLogs until the history holds n lines.
+func waitForLines(t *testing.T, d *Driver, info Info, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		r, err := d.ReadLogs(ReadConfig{Tail: -1}, info)
+		if err != nil {
+			t.Fatalf("ReadLogs: %v", err)
+		}
+		lines := readAll(t, r)
+		r.Close()
+		if len(lines) >= n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("history never reached %d lines", n)
+}
+
+func TestReadLogsFromFIFO(t *testing.T) {
+	d := newTestDriver(t)
+	info := Info{ContainerID: "c1", ContainerName: "/web"}
+	path, w := startFakeFIFO(t, d, info)
+
+	writeEntries(t, w,
+		Entry{Source: "stdout", TimeNano: 1, Line: []byte("one")},
+		Entry{Source: "stdout", TimeNano: 2, Line: []byte("tw"), Partial: true},
+		Entry{Source: "stdout", TimeNano: 3, Line: []byte("o")},
+		Entry{Source: "stderr", TimeNano: 4, Line: []byte("three")},
+	)
+	waitForLines(t, d, info, 3)
+
+	t.Run("tail", func(t *testing.T) {
+		r, err := d.ReadLogs(ReadConfig{Tail: 2}, info)
+		if err != nil {
+			t.Fatalf("ReadLogs: %v", err)
+		}
+		defer r.Close()
+		got := readAll(t, r)
+		if len(got) != 2 || got[0] != "two" || got[1] != "three" {
+			t.Errorf("got %q, want [two three]", got)
+		}
+	})
+
+	t.Run("follow", func(t *testing.T) {
+		r, err := d.ReadLogs(ReadConfig{Tail: 1, Follow: true}, info)
+		if err != nil {
+			t.Fatalf("ReadLogs: %v", err)
+		}
+		defer r.Close()
+
+		if entry, err := ReadEntry(r); err != nil || string(entry.Line) != "three" {
+			t.Fatalf("first line = %q, %v", entry.Line, err)
+		}
+		writeEntries(t, w, Entry{Source: "stdout", TimeNano: 5, Line: []byte("four")})
+		if entry, err := ReadEntry(r); err != nil || string(entry.Line) != "four" {
+			t.Fatalf("followed line = %q, %v", entry.Line, err)
+		}
+
+		if err := d.StopLogging(path); err != nil {
+			t.Fatalf("StopLogging: %v", err)
+		}
+		if _, err := ReadEntry(r); err == nil {
+			t.Fatal("follower kept going after logging stopped")
+		}
+	})
+
+	if _, err := d.ReadLogs(ReadConfig{Tail: -1}, Info{ContainerID: "unknown"}); err == nil {
+		t.Error("ReadLogs for an unknown container should fail")
+	}
+}
+
+func TestReadLogsFollowerClose(t *testing.T) {
+	d := newTestDriver(t)
+	info := Info{ContainerID: "c2"}
+	startFakeFIFO(t, d, info)
+
+	r, err := d.ReadLogs(ReadConfig{Tail: -1, Follow: true}, info)
+	if err != nil {
+		t.Fatalf("ReadLogs: %v", err)
+	}
+	reader := r.(*logReader)
+	r.Close()
+
+	select {
+	case <-reader.done:
+	case <-time.After(time.Second):
+		t.Fatal("closing the reader did not release the follower")
+	}
+}
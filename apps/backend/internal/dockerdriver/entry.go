@@ -0,0 +1,111 @@
+// Package dockerdriver implements the Docker logging plugin protocol so that
+// containers started with --log-driver logless ship straight to LogLess.
+package dockerdriver
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aasheesh/logless/internal/utils"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// maxFrameSize bounds a single frame read from the FIFO. Docker splits lines
+// at 16KiB, so anything much larger means the stream is out of sync.
+const maxFrameSize = 1 << 20
+
+// Entry is the LogEntry message Docker writes to the FIFO, see
+// api/types/plugins/logdriver/entry.proto in moby.
+type Entry struct {
+	Source   string
+	TimeNano int64
+	Line     []byte
+	Partial  bool
+	// PartialID, PartialOrdinal and PartialLast come from PartialLogEntryMetadata.
+	PartialID      string
+	PartialOrdinal int32
+	PartialLast    bool
+}
+
+// ReadEntry reads one length-prefixed entry. The frame header is a big-endian
+// uint32 holding the size of the protobuf message that follows.
+func ReadEntry(r io.Reader) (Entry, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return Entry{}, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return Entry{}, fmt.Errorf("log entry frame of %d bytes exceeds the limit", size)
+	}
+
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return Entry{}, err
+	}
+	return decodeEntry(msg)
+}
+
+func decodeEntry(msg []byte) (Entry, error) {
+	var entry Entry
+	err := utils.ReadProtoFields(msg, func(f utils.ProtoField) error {
+		switch f.Number {
+		case 1:
+			entry.Source = string(f.Bytes)
+		case 2:
+			entry.TimeNano = int64(f.Value)
+		case 3:
+			entry.Line = f.Bytes
+		case 4:
+			entry.Partial = f.Value != 0
+		case 5:
+			return utils.ReadProtoFields(f.Bytes, func(m utils.ProtoField) error {
+				switch m.Number {
+				case 1:
+					entry.PartialLast = m.Value != 0
+				case 2:
+					entry.PartialID = string(m.Bytes)
+				case 3:
+					entry.PartialOrdinal = int32(m.Value)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to decode log entry: %w", err)
+	}
+	return entry, nil
+}
+
+// WriteEntry frames and writes one entry, the format ReadLogs responds with.
+func WriteEntry(w io.Writer, entry Entry) error {
+	var msg []byte
+	if entry.Source != "" {
+		msg = protowire.AppendTag(msg, 1, protowire.BytesType)
+		msg = protowire.AppendString(msg, entry.Source)
+	}
+	if entry.TimeNano != 0 {
+		msg = protowire.AppendTag(msg, 2, protowire.VarintType)
+		msg = protowire.AppendVarint(msg, uint64(entry.TimeNano))
+	}
+	if len(entry.Line) > 0 {
+		msg = protowire.AppendTag(msg, 3, protowire.BytesType)
+		msg = protowire.AppendBytes(msg, entry.Line)
+	}
+	if entry.Partial {
+		msg = protowire.AppendTag(msg, 4, protowire.VarintType)
+		msg = protowire.AppendVarint(msg, 1)
+	}
+
+	frame := make([]byte, 4, 4+len(msg))
+	binary.BigEndian.PutUint32(frame, uint32(len(msg)))
+	_, err := w.Write(append(frame, msg...))
+	return err
+}
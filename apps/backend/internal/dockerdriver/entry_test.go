@@ -0,0 +1,203 @@
+package dockerdriver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func frame(msg []byte) []byte {
+	out := binary.BigEndian.AppendUint32(nil, uint32(len(msg)))
+	return append(out, msg...)
+}
+
+func partialMessage(line, id string, ordinal int32, last bool) []byte {
+	var meta []byte
+	if last {
+		meta = protowire.AppendTag(meta, 1, protowire.VarintType)
+		meta = protowire.AppendVarint(meta, 1)
+	}
+	meta = protowire.AppendTag(meta, 2, protowire.BytesType)
+	meta = protowire.AppendString(meta, id)
+	meta = protowire.AppendTag(meta, 3, protowire.VarintType)
+	meta = protowire.AppendVarint(meta, uint64(ordinal))
+
+	var msg []byte
+	msg = protowire.AppendTag(msg, 1, protowire.BytesType)
+	msg = protowire.AppendString(msg, "stdout")
+	msg = protowire.AppendTag(msg, 3, protowire.BytesType)
+	msg = protowire.AppendString(msg, line)
+	// Moby sets partial on every piece that carries metadata, the last too.
+	msg = protowire.AppendTag(msg, 4, protowire.VarintType)
+	msg = protowire.AppendVarint(msg, 1)
+	msg = protowire.AppendTag(msg, 5, protowire.BytesType)
+	return protowire.AppendBytes(msg, meta)
+}
+
+func TestWriteEntryRoundTrip(t *testing.T) {
+	tests := []Entry{
+		{Source: "stdout", TimeNano: 1700000000123456789, Line: []byte("hello")},
+		{Source: "stderr", TimeNano: 1, Line: []byte("piece"), Partial: true},
+		{Line: []byte("no source or time")},
+	}
+	for _, want := range tests {
+		var buf bytes.Buffer
+		if err := WriteEntry(&buf, want); err != nil {
+			t.Fatalf("WriteEntry(%+v): %v", want, err)
+		}
+		got, err := ReadEntry(&buf)
+		if err != nil {
+			t.Fatalf("ReadEntry: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("round trip = %+v, want %+v", got, want)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("%d bytes left after one frame", buf.Len())
+		}
+	}
+}
+
+func TestReadEntry(t *testing.T) {
+	oversized := binary.BigEndian.AppendUint32(nil, maxFrameSize+1)
+
+	tests := []struct {
+		name    string
+		input   []byte
+		want    Entry
+		wantErr error
+		anyErr  bool
+	}{
+		{
+			name:  "partial metadata",
+			input: frame(partialMessage("abc", "p1", 2, true)),
+			want:  Entry{Source: "stdout", Line: []byte("abc"), Partial: true, PartialID: "p1", PartialOrdinal: 2, PartialLast: true},
+		},
+		{name: "empty stream", input: nil, wantErr: io.EOF},
+		{name: "short header", input: []byte{0, 0}, wantErr: io.ErrUnexpectedEOF},
+		{name: "truncated message", input: append(binary.BigEndian.AppendUint32(nil, 10), 1, 2, 3), wantErr: io.ErrUnexpectedEOF},
+		{name: "header without message", input: binary.BigEndian.AppendUint32(nil, 4), wantErr: io.ErrUnexpectedEOF},
+		{name: "oversized frame", input: oversized, anyErr: true},
+		{name: "malformed protobuf", input: frame([]byte{0x0a, 0x05, 'a'}), anyErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadEntry(bytes.NewReader(tt.input))
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.anyErr:
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case !reflect.DeepEqual(got, tt.want):
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	tests := []struct {
+		name   string
+		pieces []Entry
+		want   []string
+	}{
+		{
+			name:   "complete line",
+			pieces: []Entry{{Source: "stdout", Line: []byte("one")}},
+			want:   []string{"one"},
+		},
+		{
+			name: "legacy partial flag",
+			pieces: []Entry{
+				{Source: "stdout", Line: []byte("ab"), Partial: true},
+				{Source: "stdout", Line: []byte("cd"), Partial: true},
+				{Source: "stdout", Line: []byte("ef")},
+			},
+			want: []string{"abcdef"},
+		},
+		{
+			name: "partial metadata",
+			pieces: []Entry{
+				{Source: "stdout", Line: []byte("ab"), Partial: true, PartialID: "x", PartialOrdinal: 1},
+				{Source: "stdout", Line: []byte("cd"), Partial: true, PartialID: "x", PartialOrdinal: 2, PartialLast: true},
+			},
+			want: []string{"abcd"},
+		},
+		{
+			name: "interleaved partial metadata",
+			pieces: []Entry{
+				{Source: "stdout", Line: []byte("a1"), Partial: true, PartialID: "a", PartialOrdinal: 1},
+				{Source: "stdout", Line: []byte("b1"), Partial: true, PartialID: "b", PartialOrdinal: 1},
+				{Source: "stdout", Line: []byte("a2"), Partial: true, PartialID: "a", PartialOrdinal: 2, PartialLast: true},
+				{Source: "stdout", Line: []byte("b2"), Partial: true, PartialID: "b", PartialOrdinal: 2, PartialLast: true},
+			},
+			want: []string{"a1a2", "b1b2"},
+		},
+		{
+			name: "streams do not mix",
+			pieces: []Entry{
+				{Source: "stdout", Line: []byte("out-"), Partial: true},
+				{Source: "stderr", Line: []byte("err")},
+				{Source: "stdout", Line: []byte("end")},
+			},
+			want: []string{"err", "out-end"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			partials := make(map[string]*Entry)
+			var got []string
+			for _, piece := range tt.pieces {
+				if entry, ok := assemble(partials, piece); ok {
+					got = append(got, string(entry.Line))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if len(partials) != 0 {
+				t.Errorf("%d partial lines left over", len(partials))
+			}
+		})
+	}
+}
+
+func TestAssembleWireFrames(t *testing.T) {
+	partials := make(map[string]*Entry)
+	var got []string
+	for _, msg := range [][]byte{
+		partialMessage("he", "p1", 1, false),
+		partialMessage("llo", "p1", 2, true),
+	} {
+		piece, err := ReadEntry(bytes.NewReader(frame(msg)))
+		if err != nil {
+			t.Fatalf("ReadEntry: %v", err)
+		}
+		if entry, ok := assemble(partials, piece); ok {
+			got = append(got, string(entry.Line))
+		}
+	}
+	if !reflect.DeepEqual(got, []string{"hello"}) || len(partials) != 0 {
+		t.Errorf("got %q with %d pending, want [hello] and none pending", got, len(partials))
+	}
+}
+
+func TestAssembleCapsPartialSize(t *testing.T) {
+	partials := make(map[string]*Entry)
+	big := bytes.Repeat([]byte("x"), maxPartialSize)
+	entry, ok := assemble(partials, Entry{Source: "stdout", Line: big, Partial: true})
+	if !ok || len(entry.Line) != maxPartialSize {
+		t.Fatalf("oversized partial was held back: ok=%v len=%d", ok, len(entry.Line))
+	}
+}
@@ -0,0 +1,136 @@
+package dockerdriver
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// history is a bounded buffer of a container's recent lines. Followers wait on
+// notify, which is closed and replaced whenever a line arrives or logging
+// stops.
+type history struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+	total   int
+	stopped bool
+	notify  chan struct{}
+}
+
+func newHistory(size int) *history {
+	return &history{entries: make([]Entry, size), notify: make(chan struct{})}
+}
+
+func (h *history) append(entry Entry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries[h.next] = entry
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+	h.total++
+	h.wake()
+}
+
+// restart is called when a stopped container starts again.
+func (h *history) restart() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stopped = false
+}
+
+func (h *history) stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stopped = true
+	h.wake()
+}
+
+func (h *history) isStopped() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.stopped
+}
+
+func (h *history) wake() {
+	close(h.notify)
+	h.notify = make(chan struct{})
+}
+
+// snapshot returns the buffered entries in order, the number of entries ever
+// appended, whether logging has stopped and the channel to wait on for more.
+func (h *history) snapshot() ([]Entry, int, bool, <-chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var entries []Entry
+	if h.full {
+		entries = append(entries, h.entries[h.next:]...)
+	}
+	entries = append(entries, h.entries[:h.next]...)
+	return entries, h.total, h.stopped, h.notify
+}
+
+// read writes the matching entries to w and, when following, keeps writing
+// new ones until logging stops or done is closed.
+func (h *history) read(config ReadConfig, w io.Writer, done <-chan struct{}) error {
+	entries, sent, stopped, notify := h.snapshot()
+
+	var matched []Entry
+	for _, entry := range entries {
+		if config.matches(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	if config.Tail >= 0 && len(matched) > config.Tail {
+		matched = matched[len(matched)-config.Tail:]
+	}
+	for _, entry := range matched {
+		if err := WriteEntry(w, entry); err != nil {
+			return err
+		}
+	}
+	if !config.Follow || stopped {
+		return nil
+	}
+
+	for {
+		select {
+		case <-notify:
+		case <-done:
+			return nil
+		}
+		var total int
+		entries, total, stopped, notify = h.snapshot()
+
+		// Lines appended since the last snapshot are at the end of the buffer.
+		start := max(len(entries)-(total-sent), 0)
+		for _, entry := range entries[start:] {
+			if !config.matches(entry) {
+				continue
+			}
+			if err := WriteEntry(w, entry); err != nil {
+				return err
+			}
+		}
+		sent = total
+		if stopped {
+			return nil
+		}
+	}
+}
+
+func (c ReadConfig) matches(entry Entry) bool {
+	ts := time.Unix(0, entry.TimeNano)
+	if !c.Since.IsZero() && ts.Before(c.Since) {
+		return false
+	}
+	if !c.Until.IsZero() && ts.After(c.Until) {
+		return false
+	}
+	return true
+}
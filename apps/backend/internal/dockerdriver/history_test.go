@@ -0,0 +1,97 @@
+package dockerdriver
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+func readAll(t *testing.T, r io.Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		entry, err := ReadEntry(r)
+		if err == io.EOF {
+			return lines
+		}
+		if err != nil {
+			t.Fatalf("ReadEntry: %v", err)
+		}
+		lines = append(lines, string(entry.Line))
+	}
+}
+
+func TestHistoryRead(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := newHistory(3)
+	for i := 1; i <= 4; i++ {
+		h.append(Entry{Line: []byte(fmt.Sprint(i)), TimeNano: base.Add(time.Duration(i) * time.Second).UnixNano()})
+	}
+
+	tests := []struct {
+		name   string
+		config ReadConfig
+		want   []string
+	}{
+		{name: "everything buffered", config: ReadConfig{Tail: -1}, want: []string{"2", "3", "4"}},
+		{name: "tail", config: ReadConfig{Tail: 2}, want: []string{"3", "4"}},
+		{name: "tail zero", config: ReadConfig{Tail: 0}, want: nil},
+		{name: "since", config: ReadConfig{Tail: -1, Since: base.Add(3 * time.Second)}, want: []string{"3", "4"}},
+		{name: "until", config: ReadConfig{Tail: -1, Until: base.Add(3 * time.Second)}, want: []string{"2", "3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := h.read(tt.config, &buf, nil); err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if got := readAll(t, &buf); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistoryFollowStopsWhenDone(t *testing.T) {
+	h := newHistory(10)
+	done := make(chan struct{})
+	returned := make(chan error, 1)
+	go func() {
+		returned <- h.read(ReadConfig{Tail: -1, Follow: true}, io.Discard, done)
+	}()
+
+	select {
+	case <-returned:
+		t.Fatal("follower returned before done was closed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(done)
+	select {
+	case err := <-returned:
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("follower still waiting for a line after done was closed")
+	}
+}
+
+func TestHistoryFollowStopsWhenLoggingStops(t *testing.T) {
+	h := newHistory(10)
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(h.read(ReadConfig{Tail: -1, Follow: true}, w, nil))
+	}()
+
+	h.append(Entry{Line: []byte("a")})
+	if entry, err := ReadEntry(r); err != nil || string(entry.Line) != "a" {
+		t.Fatalf("ReadEntry = %q, %v", entry.Line, err)
+	}
+	h.stop()
+	if _, err := ReadEntry(r); err != io.EOF {
+		t.Fatalf("after stop: %v, want EOF", err)
+	}
+}
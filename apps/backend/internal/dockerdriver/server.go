@@ -0,0 +1,118 @@
+package dockerdriver
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type startLoggingRequest struct {
+	File string
+	Info Info
+}
+
+type stopLoggingRequest struct {
+	File string
+}
+
+type readLogsRequest struct {
+	Config ReadConfig
+	Info   Info
+}
+
+type pluginResponse struct {
+	Err string `json:"Err"`
+}
+
+// NewRouter serves the plugin protocol the Docker daemon speaks over the
+// plugin's unix socket.
+func NewRouter(driver *Driver) http.Handler {
+	router := mux.NewRouter()
+	router.HandleFunc("/Plugin.Activate", func(w http.ResponseWriter, r *http.Request) {
+		respondWithPluginJSON(w, map[string][]string{"Implements": {"LogDriver"}})
+	}).Methods("POST")
+
+	router.HandleFunc("/LogDriver.Capabilities", func(w http.ResponseWriter, r *http.Request) {
+		respondWithPluginJSON(w, map[string]map[string]bool{"Cap": {"ReadLogs": true}})
+	}).Methods("POST")
+
+	router.HandleFunc("/LogDriver.StartLogging", func(w http.ResponseWriter, r *http.Request) {
+		var req startLoggingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		respondWithPluginError(w, driver.StartLogging(req.File, req.Info))
+	}).Methods("POST")
+
+	router.HandleFunc("/LogDriver.StopLogging", func(w http.ResponseWriter, r *http.Request) {
+		var req stopLoggingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		respondWithPluginError(w, driver.StopLogging(req.File))
+	}).Methods("POST")
+
+	router.HandleFunc("/LogDriver.ReadLogs", func(w http.ResponseWriter, r *http.Request) {
+		var req readLogsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		stream, err := driver.ReadLogs(req.Config, req.Info)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer stream.Close()
+		go func() {
+			// Unblock a follower once the client goes away.
+			<-r.Context().Done()
+			stream.Close()
+		}()
+
+		w.Header().Set("Content-Type", "application/x-json-stream")
+		flusher, _ := w.(http.Flusher)
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := stream.Read(buf)
+			if n > 0 {
+				if _, werr := w.Write(buf[:n]); werr != nil {
+					return
+				}
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			if err != nil {
+				if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrClosedPipe) {
+					log.Printf("Failed to stream logs for %s: %v", req.Info.ContainerID, err)
+				}
+				return
+			}
+		}
+	}).Methods("POST")
+
+	return router
+}
+
+func respondWithPluginError(w http.ResponseWriter, err error) {
+	var response pluginResponse
+	if err != nil {
+		response.Err = err.Error()
+	}
+	respondWithPluginJSON(w, response)
+}
+
+func respondWithPluginJSON(w http.ResponseWriter, payload interface{}) {
+	w.Header().Set("Content-Type", "application/vnd.docker.plugins.v1+json")
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		log.Printf("Failed to write plugin response: %v", err)
+	}
+}
@@ -12,61 +12,115 @@ import (
 	"time"
 
 	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/pipeline"
+	"github.com/aasheesh/logless/internal/severity"
 	"github.com/aasheesh/logless/internal/storage"
+	"github.com/aasheesh/logless/internal/textlog"
 )
 
 type LogService struct {
-	storage storage.LogStorage
+	storage  storage.LogStorage
+	pipeline *pipeline.Pipeline
 }
 
-func NewLogService(storage storage.LogStorage) *LogService {
-	return &LogService{storage: storage}
+// NewLogService builds the service; pipeline may be nil when entries are
+// stored as they arrive.
+func NewLogService(storage storage.LogStorage, pipeline *pipeline.Pipeline) *LogService {
+	return &LogService{storage: storage, pipeline: pipeline}
 }
 
 func (s *LogService) ProcessLogs(ctx context.Context, entries []models.LogEntry) error {
-    var (
-        levels      []string
-        compresseds [][]byte
-        jsons       []string
-    )
-
-    for _, entry := range entries {
-        if entry.Level == "" {
-            entry.Level = "info"
-        }
-        if entry.Timestamp.IsZero() {
-            entry.Timestamp = time.Now()
-        }
-
-        data, err := json.Marshal(entry)
-        if err != nil {
-            log.Printf("Marshal error: %v", err)
-            continue
-        }
-
-        var compressed bytes.Buffer
-        gz := gzip.NewWriter(&compressed)
-        if _, err := gz.Write(data); err != nil {
-            log.Printf("Compression error: %v", err)
-            continue
-        }
-        gz.Close()
-
-        levels = append(levels, entry.Level)
-        compresseds = append(compresseds, compressed.Bytes())
-        jsons = append(jsons, string(data))
-    }
-
-    return s.storage.SaveLog(ctx, levels, compresseds, jsons)
+	var rows []storage.LogRow
+
+	for _, entry := range entries {
+		// The context map is shared with the caller, who may retry the same
+		// entries after a failed save.
+		entry.Context = entry.Context.Clone()
+		level := prepare(&entry)
+		if s.pipeline != nil {
+			if !s.pipeline.Run(&entry) {
+				continue
+			}
+			// Processors may have added context keys or levels that need
+			// the same treatment.
+			level = prepare(&entry)
+		}
+		if entry.Timestamp.IsZero() {
+			entry.Timestamp = time.Now()
+		}
+
+		data, err := json.Marshal(entry)
+		if err != nil {
+			log.Printf("Marshal error: %v", err)
+			continue
+		}
+
+		var contextJSON []byte
+		if len(entry.Context) > 0 {
+			contextJSON, _ = json.Marshal(entry.Context)
+		}
+
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		if _, err := gz.Write(data); err != nil {
+			log.Printf("Compression error: %v", err)
+			continue
+		}
+		gz.Close()
+
+		rows = append(rows, storage.LogRow{
+			Level:       entry.Level,
+			Severity:    int(level),
+			Service:     entry.Service,
+			Environment: entry.Environment,
+			Host:        entry.Host,
+			TraceID:     entry.TraceID,
+			SpanID:      entry.SpanID,
+			Source:      entry.Source,
+			RepeatCount: entry.RepeatCount,
+			Context:     contextJSON,
+			Data:        compressed.Bytes(),
+			Text:        string(data),
+		})
+	}
+
+	return s.storage.SaveLog(ctx, rows)
 }
 
-func (s *LogService) GetPaginatedLogs(ctx context.Context, page, pageSize int) (*models.PaginatedLogsResponse, error) {
+// prepare promotes the well-known fields and settles the entry level.
+func prepare(entry *models.LogEntry) severity.Severity {
+	entry.PromoteFields()
+	if entry.Level == "" {
+		entry.Level = textlog.DetectLevel(entry.Message)
+	}
+	return normalizeLevel(entry)
+}
+
+// normalizeLevel rewrites the entry level onto the canonical scale. Levels
+// the alias table does not know become info, with the original kept in the
+// context so nothing is lost.
+func normalizeLevel(entry *models.LogEntry) severity.Severity {
+	level, ok := severity.Parse(entry.Level)
+	if !ok {
+		if entry.Level != "" {
+			if entry.Context == nil {
+				entry.Context = make(models.Context)
+			}
+			entry.Context["original_level"] = entry.Level
+		}
+		level = severity.Info
+	}
+	entry.Level = level.String()
+	return level
+}
+
+func (s *LogService) GetPaginatedLogs(ctx context.Context, filter models.LogFilter, page, pageSize int) (*models.PaginatedLogsResponse, error) {
 	if page < 1 || pageSize < 1 {
 		return nil, errors.New("invalid pagination parameters")
 	}
 
 	offset := (page - 1) * pageSize
-	compressedLogs, err := s.storage.GetPaginatedLogs(ctx, pageSize, offset)
+	compressedLogs, err := s.storage.GetPaginatedLogs(ctx, filter, pageSize, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get paginated logs: %w", err)
 	}
@@ -76,7 +130,7 @@ func (s *LogService) GetPaginatedLogs(ctx context.Context, page, pageSize int) (
 		return nil, fmt.Errorf("failed to decompress logs: %w", err)
 	}
 
-	totalCount, err := s.storage.GetLogsCount(ctx)
+	totalCount, err := s.storage.GetLogsCount(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get logs count: %w", err)
 	}
@@ -90,34 +144,12 @@ func (s *LogService) GetPaginatedLogs(ctx context.Context, page, pageSize int) (
 	}, nil
 }
 
-func (s *LogService) GetDateRangeLogs(ctx context.Context, startDate, endDate time.Time, page, pageSize int) (*models.PaginatedLogsResponse, error){
-	 if endDate.Before(startDate) {
-        return nil, errors.New("end date cannot be before start date")
-    }
-    
-    offset := (page - 1) * pageSize
-    compressedLogs, err := s.storage.GetDateRangeLogs(ctx, startDate, endDate, pageSize, offset)
-    if err != nil {
-        return nil, fmt.Errorf("failed to get date range logs: %w", err)
-    }
-    
-    logs, err := s.decompressLogs(compressedLogs)
-    if err != nil {
-        return nil, fmt.Errorf("failed to decompress logs: %w", err)
-    }
-    
-    totalCount, err := s.storage.GetDateRangeLogsCount(ctx, startDate, endDate)
-    if err != nil {
-        return nil, fmt.Errorf("failed to get date range logs count: %w", err)
-    }
-    
-    return &models.PaginatedLogsResponse{
-        Data:       logs,
-        Page:       page,
-        PageSize:   pageSize,
-        TotalCount: totalCount,
-        TotalPages: int(math.Ceil(float64(totalCount) / float64(pageSize))),
-    }, nil
+func (s *LogService) GetDateRangeLogs(ctx context.Context, filter models.LogFilter, page, pageSize int) (*models.PaginatedLogsResponse, error) {
+	if filter.End.Before(filter.Start) {
+		return nil, errors.New("end date cannot be before start date")
+	}
+
+	return s.GetPaginatedLogs(ctx, filter, page, pageSize)
 }
 
 func (s *LogService) GetLevelLogs(ctx context.Context, level string) ([][]byte, error) {
@@ -125,6 +157,10 @@ func (s *LogService) GetLevelLogs(ctx context.Context, level string) ([][]byte,
 		return nil, errors.New("level cannot be empty")
 	}
 
+	if canonical, ok := severity.Parse(level); ok {
+		level = canonical.String()
+	}
+
 	compressedLogs, err := s.storage.GetLevelLogs(ctx, level)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get level logs: %w", err)
@@ -150,6 +186,9 @@ func (s *LogService) SetLevelColors(ctx context.Context, level, color string) er
 	if level == "" || color == "" {
 		return errors.New("level and color cannot be empty")
 	}
+	if canonical, ok := severity.Parse(level); ok {
+		level = canonical.String()
+	}
 
 	return s.storage.SetLevelColors(ctx, level, color)
 }
@@ -179,7 +218,7 @@ func (s *LogService) decompressLogs(compressedLogs [][]byte) ([][]byte, error) {
 
 func (s *LogService) HealthCheck(ctx context.Context) (*models.HealthResponse, error) {
 	// Check database connection
-	if _, err := s.storage.GetLogsCount(ctx); err != nil {
+	if err := s.storage.Ping(ctx); err != nil {
 		return nil, fmt.Errorf("database connection failed: %w", err)
 	}
 
@@ -0,0 +1,149 @@
+package elastic
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/utils"
+)
+
+// BulkItem is one action/source pair from a _bulk request. Err is set when the
+// pair cannot be turned into a log entry; the rest of the request still proceeds.
+type BulkItem struct {
+	Action string
+	Index  string
+	ID     string
+	Entry  models.LogEntry
+	Err    *ItemError
+}
+
+type ItemError struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+type actionMeta struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+// ParseBulk splits an NDJSON _bulk body into items. Only index and create
+// actions carry documents LogLess can store; update and delete are reported
+// back as per-item errors.
+func ParseBulk(body []byte, defaultIndex string) ([]BulkItem, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), len(body)+1)
+
+	var items []BulkItem
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var action map[string]actionMeta
+		if err := json.Unmarshal(line, &action); err != nil || len(action) != 1 {
+			return nil, fmt.Errorf("malformed action/metadata line [%s]", line)
+		}
+
+		var item BulkItem
+		var meta actionMeta
+		for name, m := range action {
+			item.Action, meta = name, m
+		}
+		item.Index = meta.Index
+		if item.Index == "" {
+			item.Index = defaultIndex
+		}
+		item.ID = meta.ID
+
+		switch item.Action {
+		case "index", "create", "update":
+			if !scanner.Scan() {
+				return nil, fmt.Errorf("action [%s] is missing its source line", item.Action)
+			}
+		case "delete":
+		default:
+			return nil, fmt.Errorf("unknown action [%s]", item.Action)
+		}
+
+		if item.ID == "" {
+			item.ID = newID()
+		}
+
+		switch {
+		case item.Action == "update" || item.Action == "delete":
+			item.Err = &ItemError{
+				Type:   "action_request_validation_exception",
+				Reason: fmt.Sprintf("%s is not supported, log entries are append-only", item.Action),
+			}
+		case item.Index == "":
+			item.Err = &ItemError{Type: "action_request_validation_exception", Reason: "index is missing"}
+		default:
+			entry, err := documentToEntry(scanner.Bytes(), item.Index)
+			if err != nil {
+				item.Err = &ItemError{Type: "document_parsing_exception", Reason: err.Error()}
+			}
+			item.Entry = entry
+		}
+
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// documentToEntry maps a source document onto a LogEntry. ECS style fields
+// (message, log.level, @timestamp) are recognised alongside the common plain
+// names; everything else is kept in the context with its nesting intact.
+func documentToEntry(source []byte, index string) (models.LogEntry, error) {
+	decoder := json.NewDecoder(bytes.NewReader(source))
+	decoder.UseNumber()
+
+	var doc map[string]any
+	if err := decoder.Decode(&doc); err != nil {
+		return models.LogEntry{}, fmt.Errorf("failed to parse source document: %v", err)
+	}
+	if doc == nil {
+		return models.LogEntry{}, errors.New("source document must be an object")
+	}
+
+	context := models.Context(doc)
+
+	entry := models.LogEntry{
+		Message: utils.TakeFirst(context, "message", "msg", "log", "event.original"),
+		Level:   strings.ToLower(utils.TakeFirst(context, "log.level", "level", "severity")),
+	}
+	if entry.Message == "" {
+		entry.Message = string(bytes.TrimSpace(source))
+	}
+
+	if ts := utils.TakeFirst(context, "@timestamp", "timestamp"); ts != "" {
+		parsed, err := models.ParseTimestampString(ts)
+		if err != nil {
+			return models.LogEntry{}, fmt.Errorf("failed to parse timestamp [%s]: %v", ts, err)
+		}
+		entry.Timestamp = parsed
+	}
+
+	entry.Service = index
+	if len(context) > 0 {
+		entry.Context = context
+	}
+	return entry, nil
+}
+
+func newID() string {
+	var b [10]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
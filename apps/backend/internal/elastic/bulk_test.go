@@ -0,0 +1,159 @@
+package elastic
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+)
+
+func TestParseBulk(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		index   string
+		want    []BulkItem
+		wantErr bool
+	}{
+		{
+			name: "index and create",
+			body: `{"index":{"_index":"web","_id":"1"}}
+{"message":"hello"}
+
+{"create":{"_id":"2"}}
+{"msg":"second"}
+`,
+			index: "default",
+			want: []BulkItem{
+				{Action: "index", Index: "web", ID: "1", Entry: models.LogEntry{Message: "hello", Service: "web"}},
+				{Action: "create", Index: "default", ID: "2", Entry: models.LogEntry{Message: "second", Service: "default"}},
+			},
+		},
+		{
+			name: "update and delete are rejected per item",
+			body: `{"update":{"_index":"web","_id":"1"}}
+{"doc":{"a":1}}
+{"delete":{"_index":"web","_id":"2"}}
+{"index":{"_index":"web","_id":"3"}}
+{"message":"kept"}
+`,
+			want: []BulkItem{
+				{Action: "update", Index: "web", ID: "1", Err: &ItemError{Type: "action_request_validation_exception", Reason: "update is not supported, log entries are append-only"}},
+				{Action: "delete", Index: "web", ID: "2", Err: &ItemError{Type: "action_request_validation_exception", Reason: "delete is not supported, log entries are append-only"}},
+				{Action: "index", Index: "web", ID: "3", Entry: models.LogEntry{Message: "kept", Service: "web"}},
+			},
+		},
+		{
+			name: "missing index",
+			body: "{\"index\":{\"_id\":\"1\"}}\n{\"message\":\"a\"}\n",
+			want: []BulkItem{{Action: "index", ID: "1", Err: &ItemError{Type: "action_request_validation_exception", Reason: "index is missing"}}},
+		},
+		{name: "unknown action", body: "{\"upsert\":{}}\n{}\n", index: "i", wantErr: true},
+		{name: "two actions on one line", body: "{\"index\":{},\"create\":{}}\n{}\n", index: "i", wantErr: true},
+		{name: "missing source line", body: "{\"index\":{}}\n", index: "i", wantErr: true},
+		{name: "not json", body: "hello\n", index: "i", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBulk([]byte(tt.body), tt.index)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseBulk: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBulkGeneratesIDs(t *testing.T) {
+	items, err := ParseBulk([]byte("{\"index\":{}}\n{\"message\":\"a\"}\n{\"index\":{}}\n{\"message\":\"b\"}\n"), "i")
+	if err != nil {
+		t.Fatalf("ParseBulk: %v", err)
+	}
+	if len(items) != 2 || len(items[0].ID) != 20 || items[0].ID == items[1].ID {
+		t.Errorf("generated IDs = %q, %q, want two distinct 20 character IDs", items[0].ID, items[1].ID)
+	}
+}
+
+func TestDocumentToEntry(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		source  string
+		want    models.LogEntry
+		wantErr bool
+	}{
+		{
+			name:   "ECS fields",
+			source: `{"@timestamp":"2024-05-01T10:00:00Z","message":"hi","log":{"level":"WARN","logger":"x"},"host":{"name":"h1"}}`,
+			want: models.LogEntry{
+				Message:   "hi",
+				Level:     "warn",
+				Timestamp: ts,
+				Service:   "idx",
+				Context:   models.Context{"log": map[string]any{"logger": "x"}, "host": map[string]any{"name": "h1"}},
+			},
+		},
+		{
+			name:   "plain fields and numbers",
+			source: `{"timestamp":"2024-05-01T10:00:00Z","msg":"hi","level":"error","took":12}`,
+			want: models.LogEntry{
+				Message:   "hi",
+				Level:     "error",
+				Timestamp: ts,
+				Service:   "idx",
+				Context:   models.Context{"took": json.Number("12")},
+			},
+		},
+		{
+			name:   "no message keeps the source",
+			source: ` {"k":"v"} `,
+			want:   models.LogEntry{Message: `{"k":"v"}`, Service: "idx", Context: models.Context{"k": "v"}},
+		},
+		{
+			name:   "object message is not flattened",
+			source: `{"message":{"a":1},"log":"line"}`,
+			want:   models.LogEntry{Message: "line", Service: "idx", Context: models.Context{"message": map[string]any{"a": json.Number("1")}}},
+		},
+		{name: "array source", source: `[1]`, wantErr: true},
+		{name: "null source", source: `null`, wantErr: true},
+		{name: "bad timestamp", source: `{"message":"a","@timestamp":"someday"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := documentToEntry([]byte(tt.source), "idx")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("documentToEntry: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBulkDocumentError(t *testing.T) {
+	items, err := ParseBulk([]byte("{\"index\":{\"_index\":\"i\"}}\n[1]\n"), "")
+	if err != nil {
+		t.Fatalf("ParseBulk: %v", err)
+	}
+	if items[0].Err == nil || items[0].Err.Type != "document_parsing_exception" || !strings.Contains(items[0].Err.Reason, "failed to parse source document") {
+		t.Errorf("item error = %+v, want a document_parsing_exception", items[0].Err)
+	}
+}
@@ -0,0 +1,47 @@
+package elastic
+
+// The response types follow the shape of Elasticsearch's _bulk response
+// closely enough for Beats, Logstash and Vector to report per-document
+// results.
+
+type BulkResponse struct {
+	Took   int64                       `json:"took"`
+	Errors bool                        `json:"errors"`
+	Items  []map[string]BulkItemResult `json:"items"`
+}
+
+type BulkItemResult struct {
+	Index   string     `json:"_index"`
+	ID      string     `json:"_id"`
+	Version int        `json:"_version,omitempty"`
+	Result  string     `json:"result,omitempty"`
+	Status  int        `json:"status"`
+	Error   *ItemError `json:"error,omitempty"`
+}
+
+// InfoResponse answers the root endpoint that shippers probe before sending.
+type InfoResponse struct {
+	Name        string      `json:"name"`
+	ClusterName string      `json:"cluster_name"`
+	Version     VersionInfo `json:"version"`
+	Tagline     string      `json:"tagline"`
+}
+
+type VersionInfo struct {
+	Number        string `json:"number"`
+	BuildFlavor   string `json:"build_flavor"`
+	LuceneVersion string `json:"lucene_version"`
+}
+
+func NewInfoResponse() InfoResponse {
+	return InfoResponse{
+		Name:        "logless",
+		ClusterName: "logless",
+		Version: VersionInfo{
+			Number:        "8.11.0",
+			BuildFlavor:   "default",
+			LuceneVersion: "9.8.0",
+		},
+		Tagline: "You Know, for Search",
+	}
+}
@@ -0,0 +1,306 @@
+package fluent
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/utils"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+const maxDecompressedChunkSize = 64 << 20
+
+// maxCollectionLen and maxDepth bound the arrays and maps a frame may
+// declare, since a few bytes can announce millions of elements.
+const (
+	maxCollectionLen = 1 << 20
+	maxDepth         = 64
+)
+
+// EventTime is the Forward protocol's nanosecond timestamp, carried as
+// MessagePack extension type 0.
+type EventTime struct {
+	time.Time
+}
+
+func init() {
+	msgpack.RegisterExt(0, (*EventTime)(nil))
+}
+
+func (t *EventTime) MarshalMsgpack() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, uint32(t.Unix()))
+	binary.BigEndian.PutUint32(b[4:], uint32(t.Nanosecond()))
+	return b, nil
+}
+
+func (t *EventTime) UnmarshalMsgpack(b []byte) error {
+	if len(b) != 8 {
+		return fmt.Errorf("invalid EventTime length %d", len(b))
+	}
+	sec := binary.BigEndian.Uint32(b)
+	nsec := binary.BigEndian.Uint32(b[4:])
+	t.Time = time.Unix(int64(sec), int64(nsec)).UTC()
+	return nil
+}
+
+// message is one decoded Forward protocol frame.
+type message struct {
+	tag     string
+	entries []models.LogEntry
+	chunk   string
+}
+
+// decodeMessage reads a single frame in any of the four carrier modes:
+// Message, Forward, PackedForward and CompressedPackedForward.
+func decodeMessage(dec *msgpack.Decoder) (*message, error) {
+	n, err := dec.DecodeArrayLen()
+	if err != nil {
+		return nil, err
+	}
+	if n < 2 || n > 4 {
+		return nil, fmt.Errorf("unexpected frame length %d", n)
+	}
+
+	tag, err := dec.DecodeString()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode tag: %w", err)
+	}
+
+	second, err := decodeValue(dec, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode frame: %w", err)
+	}
+
+	// Message mode is [tag, time, record, option?]; every other mode has at
+	// most an option map after the entries.
+	var (
+		record any
+		option map[string]any
+	)
+	rest := n - 2
+	if isTime(second) {
+		if rest == 0 {
+			return nil, errors.New("message mode frame is missing its record")
+		}
+		if record, err = decodeValue(dec, 0); err != nil {
+			return nil, fmt.Errorf("failed to decode record: %w", err)
+		}
+		rest--
+	}
+	if rest > 0 {
+		raw, err := decodeValue(dec, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode option: %w", err)
+		}
+		option, _ = raw.(map[string]any)
+	}
+
+	msg := &message{tag: tag}
+	if chunk, ok := option["chunk"].(string); ok {
+		msg.chunk = chunk
+	}
+
+	switch val := second.(type) {
+	case []any:
+		for _, item := range val {
+			pair, ok := item.([]any)
+			if !ok || len(pair) != 2 {
+				return nil, errors.New("forward mode entry must be [time, record]")
+			}
+			entry, err := toEntry(tag, pair[0], pair[1])
+			if err != nil {
+				return nil, err
+			}
+			msg.entries = append(msg.entries, entry)
+		}
+	case []byte:
+		msg.entries, err = decodePacked(tag, val, option)
+	case string:
+		msg.entries, err = decodePacked(tag, []byte(val), option)
+	default:
+		var entry models.LogEntry
+		entry, err = toEntry(tag, second, record)
+		msg.entries = []models.LogEntry{entry}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// decodePacked handles PackedForward, whose entries are a concatenated
+// MessagePack stream, and its gzip compressed variant.
+func decodePacked(tag string, packed []byte, option map[string]any) ([]models.LogEntry, error) {
+	var r io.Reader = bytes.NewReader(packed)
+	if compressed, _ := option["compressed"].(string); compressed != "" {
+		if compressed != "gzip" {
+			return nil, fmt.Errorf("unsupported compression %q", compressed)
+		}
+		gz, err := utils.NewDecompressingReader(r, compressed, maxDecompressedChunkSize)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	dec := msgpack.NewDecoder(r)
+	var entries []models.LogEntry
+	for {
+		value, err := decodeValue(dec, 0)
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode packed entry: %w", err)
+		}
+		pair, ok := value.([]any)
+		if !ok || len(pair) != 2 {
+			return nil, errors.New("packed entry must be [time, record]")
+		}
+
+		entry, err := toEntry(tag, pair[0], pair[1])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// decodeValue decodes like DecodeInterface, except that arrays and maps are
+// checked against maxCollectionLen and maxDepth and grow with the elements
+// actually read instead of being allocated at their declared length.
+func decodeValue(dec *msgpack.Decoder, depth int) (any, error) {
+	code, err := dec.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case msgpcode.IsFixedArray(code) || code == msgpcode.Array16 || code == msgpcode.Array32:
+		n, err := dec.DecodeArrayLen()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkCollection(n, depth); err != nil {
+			return nil, err
+		}
+		var values []any
+		for range n {
+			value, err := decodeValue(dec, depth+1)
+			if err != nil {
+				return nil, noEOF(err)
+			}
+			values = append(values, value)
+		}
+		if values == nil {
+			values = []any{}
+		}
+		return values, nil
+	case msgpcode.IsFixedMap(code) || code == msgpcode.Map16 || code == msgpcode.Map32:
+		n, err := dec.DecodeMapLen()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkCollection(n, depth); err != nil {
+			return nil, err
+		}
+		values := make(map[string]any)
+		for range n {
+			key, err := dec.DecodeString()
+			if err != nil {
+				return nil, noEOF(err)
+			}
+			value, err := decodeValue(dec, depth+1)
+			if err != nil {
+				return nil, noEOF(err)
+			}
+			values[key] = value
+		}
+		return values, nil
+	}
+	return dec.DecodeInterface()
+}
+
+func checkCollection(n, depth int) error {
+	if n > maxCollectionLen {
+		return fmt.Errorf("collection of %d elements exceeds the limit of %d", n, maxCollectionLen)
+	}
+	if depth >= maxDepth {
+		return fmt.Errorf("values nested deeper than %d levels", maxDepth)
+	}
+	return nil
+}
+
+// noEOF keeps a stream that ends inside a value from reading as a clean end
+// of stream.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func isTime(v any) bool {
+	switch v.(type) {
+	case *EventTime, int8, int16, int32, int64, uint8, uint16, uint32, uint64:
+		return true
+	}
+	return false
+}
+
+func toTime(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case *EventTime:
+		return t.Time, nil
+	case int8:
+		return time.Unix(int64(t), 0).UTC(), nil
+	case int16:
+		return time.Unix(int64(t), 0).UTC(), nil
+	case int32:
+		return time.Unix(int64(t), 0).UTC(), nil
+	case int64:
+		return time.Unix(t, 0).UTC(), nil
+	case uint8:
+		return time.Unix(int64(t), 0).UTC(), nil
+	case uint16:
+		return time.Unix(int64(t), 0).UTC(), nil
+	case uint32:
+		return time.Unix(int64(t), 0).UTC(), nil
+	case uint64:
+		return time.Unix(int64(t), 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid event time %T", v)
+}
+
+// toEntry maps a record onto a LogEntry. The message comes from the usual
+// "log" (Docker, CRI) or "message" keys and the tag is kept in the context.
+func toEntry(tag string, rawTime, rawRecord any) (models.LogEntry, error) {
+	ts, err := toTime(rawTime)
+	if err != nil {
+		return models.LogEntry{}, err
+	}
+	record, ok := rawRecord.(map[string]any)
+	if !ok {
+		return models.LogEntry{}, fmt.Errorf("record must be a map, got %T", rawRecord)
+	}
+
+	context := models.Context(utils.NormalizeValue(record).(map[string]any))
+
+	entry := models.LogEntry{
+		Message:   strings.TrimRight(utils.TakeFirst(context, "message", "log", "msg"), "\n"),
+		Level:     strings.ToLower(utils.TakeFirst(context, "level", "severity")),
+		Timestamp: ts,
+	}
+	context["tag"] = tag
+	entry.Context = context
+	return entry, nil
+}
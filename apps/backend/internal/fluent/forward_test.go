@@ -0,0 +1,199 @@
+package fluent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/utils"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestEventTime(t *testing.T) {
+	tests := []struct {
+		name string
+		time time.Time
+	}{
+		{name: "nanoseconds", time: time.Date(2024, 3, 1, 12, 0, 0, 123456789, time.UTC)},
+		{name: "whole seconds", time: time.Unix(1, 0).UTC()},
+		{name: "epoch", time: time.Unix(0, 0).UTC()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := msgpack.Marshal(&EventTime{tt.time})
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			// fixext 8 with extension type 0.
+			if len(b) != 10 || b[0] != 0xd7 || b[1] != 0x00 {
+				t.Fatalf("encoded as % x, want fixext 8 of type 0", b)
+			}
+			if sec := binary.BigEndian.Uint32(b[2:]); int64(sec) != tt.time.Unix() {
+				t.Errorf("seconds = %d, want %d", sec, tt.time.Unix())
+			}
+
+			var got any
+			if err := msgpack.Unmarshal(b, &got); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			eventTime, ok := got.(*EventTime)
+			if !ok {
+				t.Fatalf("decoded %T, want *EventTime", got)
+			}
+			if !eventTime.Equal(tt.time) {
+				t.Errorf("got %v, want %v", eventTime.Time, tt.time)
+			}
+		})
+	}
+}
+
+func TestEventTimeInvalidLength(t *testing.T) {
+	var et EventTime
+	if err := et.UnmarshalMsgpack([]byte{0, 0, 0, 1}); err == nil {
+		t.Fatal("expected an error for a 4 byte EventTime")
+	}
+}
+
+func encode(t *testing.T, values ...any) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeMessage(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 5, time.UTC)
+	eventTime := &EventTime{ts}
+	record := map[string]any{"log": "hello\n", "level": "WARN", "pod": "web-1"}
+	want := models.LogEntry{
+		Message:   "hello",
+		Level:     "warn",
+		Timestamp: ts,
+		Context:   models.Context{"pod": "web-1", "tag": "app.web"},
+	}
+	wantSeconds := want
+	wantSeconds.Timestamp = ts.Truncate(time.Second)
+
+	packed := encode(t, []any{eventTime, record}, []any{ts.Unix(), record})
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(packed)
+	zw.Close()
+
+	tests := []struct {
+		name      string
+		frame     []byte
+		want      []models.LogEntry
+		wantChunk string
+	}{
+		{
+			name:  "message mode with EventTime",
+			frame: encode(t, []any{"app.web", eventTime, record}),
+			want:  []models.LogEntry{want},
+		},
+		{
+			name:      "message mode with integer time and chunk",
+			frame:     encode(t, []any{"app.web", ts.Unix(), record, map[string]any{"chunk": "abc"}}),
+			want:      []models.LogEntry{wantSeconds},
+			wantChunk: "abc",
+		},
+		{
+			name:  "forward mode",
+			frame: encode(t, []any{"app.web", []any{[]any{eventTime, record}, []any{uint32(ts.Unix()), record}}}),
+			want:  []models.LogEntry{want, wantSeconds},
+		},
+		{
+			name:  "packed forward",
+			frame: encode(t, []any{"app.web", packed}),
+			want:  []models.LogEntry{want, wantSeconds},
+		},
+		{
+			name:      "compressed packed forward",
+			frame:     encode(t, []any{"app.web", compressed.Bytes(), map[string]any{"compressed": "gzip", "chunk": "xyz"}}),
+			want:      []models.LogEntry{want, wantSeconds},
+			wantChunk: "xyz",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := decodeMessage(msgpack.NewDecoder(bytes.NewReader(tt.frame)))
+			if err != nil {
+				t.Fatalf("decodeMessage: %v", err)
+			}
+			if msg.tag != "app.web" || msg.chunk != tt.wantChunk {
+				t.Errorf("tag %q chunk %q, want app.web and %q", msg.tag, msg.chunk, tt.wantChunk)
+			}
+			if !reflect.DeepEqual(msg.entries, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", msg.entries, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeMessageErrors(t *testing.T) {
+	record := map[string]any{"message": "x"}
+	tests := []struct {
+		name  string
+		frame []any
+	}{
+		{name: "too short", frame: []any{"tag"}},
+		{name: "missing record", frame: []any{"tag", int64(1)}},
+		{name: "record is not a map", frame: []any{"tag", int64(1), "text"}},
+		{name: "forward entry without record", frame: []any{"tag", []any{[]any{int64(1)}}}},
+		{name: "invalid time", frame: []any{"tag", []any{[]any{"yesterday", record}}}},
+		{name: "unsupported compression", frame: []any{"tag", []byte{1}, map[string]any{"compressed": "zstd"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := decodeMessage(msgpack.NewDecoder(bytes.NewReader(encode(t, tt.frame))))
+			if err == nil {
+				t.Errorf("expected an error, got %+v", msg)
+			}
+		})
+	}
+}
+
+func TestDecodeMessageLimits(t *testing.T) {
+	// fixarray 2 or 3, followed by the tag "tag".
+	header := func(n byte) []byte { return []byte{0x90 | n, 0xa3, 't', 'a', 'g'} }
+	tests := []struct {
+		name  string
+		frame []byte
+	}{
+		{name: "huge array", frame: append(header(2), 0xdd, 0xff, 0xff, 0xff, 0xff)},
+		{name: "huge map", frame: append(header(3), 0x01, 0xdf, 0xff, 0xff, 0xff, 0xff)},
+		{name: "deep nesting", frame: append(append(header(2), bytes.Repeat([]byte{0x91}, maxDepth+1)...), 0xc0)},
+		{name: "array cut short", frame: append(header(2), 0x92, 0x01)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := decodeMessage(msgpack.NewDecoder(bytes.NewReader(tt.frame)))
+			if err == nil {
+				t.Errorf("expected an error, got %+v", msg)
+			}
+		})
+	}
+}
+
+func TestDecodePackedTooLarge(t *testing.T) {
+	packed := encode(t, []any{int64(1), map[string]any{"log": make([]byte, maxDecompressedChunkSize)}})
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(packed)
+	zw.Close()
+
+	_, err := decodePacked("app", compressed.Bytes(), map[string]any{"compressed": "gzip"})
+	if !errors.Is(err, utils.ErrDecompressedTooLarge) {
+		t.Fatalf("error = %v, want %v", err, utils.ErrDecompressedTooLarge)
+	}
+}
@@ -0,0 +1,133 @@
+package fluent
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+
+	producer "github.com/aasheesh/logless/internal/kafka"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Server accepts Fluentd/Fluent Bit forward output over TCP and publishes the
+// records through the Kafka producer.
+type Server struct {
+	producer *producer.LogProducer
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+	closed   bool
+}
+
+func NewServer(producer *producer.LogProducer) *Server {
+	return &Server{producer: producer, conns: make(map[net.Conn]struct{})}
+}
+
+func (s *Server) Listen(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on tcp %s: %w", addr, err)
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		ln.Close()
+		return net.ErrClosed
+	}
+	s.listener = ln
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Printf("Fluent forward accept error: %v", err)
+				}
+				return
+			}
+
+			s.mu.Lock()
+			if s.closed {
+				s.mu.Unlock()
+				conn.Close()
+				return
+			}
+			s.conns[conn] = struct{}{}
+			s.wg.Add(1)
+			s.mu.Unlock()
+
+			go func() {
+				defer s.wg.Done()
+				s.handleConn(conn)
+
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+			}()
+		}
+	}()
+
+	log.Printf("Fluent forward listener ready on tcp %s", addr)
+	return nil
+}
+
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	dec := msgpack.NewDecoder(bufio.NewReader(conn))
+	enc := msgpack.NewEncoder(conn)
+
+	for {
+		msg, err := decodeMessage(dec)
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				log.Printf("Fluent forward connection from %s closed: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		failed := false
+		for _, err := range s.producer.SendLogs(msg.entries) {
+			if err != nil {
+				failed = true
+			}
+		}
+		if failed {
+			// Without an ack the client resends the whole chunk.
+			log.Printf("Failed to send fluent forward chunk for tag %s to Kafka", msg.tag)
+			continue
+		}
+
+		if msg.chunk != "" {
+			if err := enc.Encode(map[string]string{"ack": msg.chunk}); err != nil {
+				log.Printf("Failed to ack fluent forward chunk: %v", err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package gelf
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	chunkHeaderSize = 12
+	maxChunks       = 128
+	chunkTimeout    = 5 * time.Second
+	// maxPendingMessages bounds the incomplete messages held at once, each
+	// of up to maxChunks chunks.
+	maxPendingMessages = 4096
+)
+
+var chunkMagic = [2]byte{0x1e, 0x0f}
+
+type pendingMessage struct {
+	chunks   [][]byte
+	received int
+	started  time.Time
+}
+
+// assembler reassembles chunked UDP messages. Incomplete messages are
+// dropped once they are older than chunkTimeout, as the GELF spec requires.
+type assembler struct {
+	mu      sync.Mutex
+	pending map[uint64]*pendingMessage
+}
+
+func newAssembler() *assembler {
+	return &assembler{pending: make(map[uint64]*pendingMessage)}
+}
+
+func isChunked(datagram []byte) bool {
+	return len(datagram) >= 2 && datagram[0] == chunkMagic[0] && datagram[1] == chunkMagic[1]
+}
+
+// add stores one chunk and returns the full payload once every chunk of the
+// message has arrived.
+func (a *assembler) add(datagram []byte, now time.Time) ([]byte, error) {
+	if len(datagram) < chunkHeaderSize {
+		return nil, errors.New("GELF chunk shorter than its header")
+	}
+	id := binary.BigEndian.Uint64(datagram[2:10])
+	seq, count := int(datagram[10]), int(datagram[11])
+	if count == 0 || count > maxChunks || seq >= count {
+		return nil, errors.New("invalid GELF chunk sequence")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	msg, ok := a.pending[id]
+	if !ok {
+		a.expire(now)
+		if len(a.pending) >= maxPendingMessages {
+			return nil, errors.New("too many incomplete GELF messages")
+		}
+		msg = &pendingMessage{chunks: make([][]byte, count), started: now}
+		a.pending[id] = msg
+	}
+	if len(msg.chunks) != count {
+		delete(a.pending, id)
+		return nil, errors.New("GELF chunk count changed mid-message")
+	}
+	if msg.chunks[seq] != nil {
+		return nil, nil
+	}
+
+	msg.chunks[seq] = append([]byte(nil), datagram[chunkHeaderSize:]...)
+	msg.received++
+	if msg.received < count {
+		return nil, nil
+	}
+
+	delete(a.pending, id)
+	var payload []byte
+	for _, chunk := range msg.chunks {
+		payload = append(payload, chunk...)
+	}
+	return payload, nil
+}
+
+func (a *assembler) expire(now time.Time) {
+	for id, msg := range a.pending {
+		if now.Sub(msg.started) > chunkTimeout {
+			delete(a.pending, id)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package gelf
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func chunk(id uint64, seq, count byte, data string) []byte {
+	b := append([]byte{}, chunkMagic[:]...)
+	b = binary.BigEndian.AppendUint64(b, id)
+	b = append(b, seq, count)
+	return append(b, data...)
+}
+
+func TestAssemblerAdd(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	type step struct {
+		datagram []byte
+		after    time.Duration
+		want     string
+		wantErr  bool
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name:  "single chunk",
+			steps: []step{{datagram: chunk(1, 0, 1, "whole"), want: "whole"}},
+		},
+		{
+			name: "in order",
+			steps: []step{
+				{datagram: chunk(1, 0, 3, "a")},
+				{datagram: chunk(1, 1, 3, "b")},
+				{datagram: chunk(1, 2, 3, "c"), want: "abc"},
+			},
+		},
+		{
+			name: "out of order",
+			steps: []step{
+				{datagram: chunk(1, 2, 3, "c")},
+				{datagram: chunk(1, 0, 3, "a")},
+				{datagram: chunk(1, 1, 3, "b"), want: "abc"},
+			},
+		},
+		{
+			name: "duplicate chunk is ignored",
+			steps: []step{
+				{datagram: chunk(1, 0, 2, "a")},
+				{datagram: chunk(1, 0, 2, "x")},
+				{datagram: chunk(1, 1, 2, "b"), want: "ab"},
+			},
+		},
+		{
+			name: "interleaved messages",
+			steps: []step{
+				{datagram: chunk(1, 0, 2, "a")},
+				{datagram: chunk(2, 1, 2, "y")},
+				{datagram: chunk(2, 0, 2, "x"), want: "xy"},
+				{datagram: chunk(1, 1, 2, "b"), want: "ab"},
+			},
+		},
+		{
+			name: "count changed mid-message",
+			steps: []step{
+				{datagram: chunk(1, 0, 2, "a")},
+				{datagram: chunk(1, 1, 3, "b"), wantErr: true},
+				// The message was discarded, so it starts over.
+				{datagram: chunk(1, 1, 2, "b")},
+				{datagram: chunk(1, 0, 2, "a"), want: "ab"},
+			},
+		},
+		{
+			name: "expired message starts over",
+			steps: []step{
+				{datagram: chunk(1, 0, 2, "a")},
+				// A new message id triggers expiry of the stale one.
+				{datagram: chunk(2, 0, 2, "x"), after: chunkTimeout + time.Second},
+				{datagram: chunk(1, 1, 2, "b"), after: chunkTimeout + time.Second},
+			},
+		},
+		{name: "short header", steps: []step{{datagram: chunkMagic[:], wantErr: true}}},
+		{name: "zero count", steps: []step{{datagram: chunk(1, 0, 0, "a"), wantErr: true}}},
+		{name: "sequence past count", steps: []step{{datagram: chunk(1, 2, 2, "a"), wantErr: true}}},
+		{name: "too many chunks", steps: []step{{datagram: chunk(1, 0, maxChunks+1, "a"), wantErr: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAssembler()
+			for i, s := range tt.steps {
+				payload, err := a.add(s.datagram, start.Add(s.after))
+				if s.wantErr {
+					if err == nil {
+						t.Fatalf("step %d: expected an error", i)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("step %d: %v", i, err)
+				}
+				if string(payload) != s.want {
+					t.Fatalf("step %d: payload %q, want %q", i, payload, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAssemblerPendingLimit(t *testing.T) {
+	a := newAssembler()
+	now := time.Now()
+	for id := range uint64(maxPendingMessages) {
+		if _, err := a.add(chunk(id, 0, 2, "a"), now); err != nil {
+			t.Fatalf("chunk %d: %v", id, err)
+		}
+	}
+	if _, err := a.add(chunk(maxPendingMessages, 0, 2, "a"), now); err == nil {
+		t.Fatal("expected an error once the pending limit is reached")
+	}
+	// Stale messages make room again.
+	if _, err := a.add(chunk(maxPendingMessages, 0, 2, "a"), now.Add(chunkTimeout+time.Second)); err != nil {
+		t.Fatalf("after expiry: %v", err)
+	}
+}
+
+func TestIsChunked(t *testing.T) {
+	tests := []struct {
+		datagram []byte
+		want     bool
+	}{
+		{chunk(1, 0, 1, ""), true},
+		{[]byte{0x1e}, false},
+		{[]byte(`{"short_message":"x"}`), false},
+		{[]byte{0x78, 0x9c}, false},
+	}
+	for _, tt := range tests {
+		if got := isChunked(tt.datagram); got != tt.want {
+			t.Errorf("isChunked(% x) = %v, want %v", tt.datagram, got, tt.want)
+		}
+	}
+}
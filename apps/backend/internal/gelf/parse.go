@@ -0,0 +1,104 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/syslog"
+)
+
+const maxDecompressedSize = 8 << 20
+
+// Parse decodes a complete GELF payload. UDP payloads may be zlib or gzip
+// compressed; the compression is detected from the magic bytes.
+func Parse(payload []byte) (models.LogEntry, error) {
+	data, err := decompress(payload)
+	if err != nil {
+		return models.LogEntry{}, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var fields map[string]any
+	if err := decoder.Decode(&fields); err != nil {
+		return models.LogEntry{}, fmt.Errorf("invalid GELF payload: %w", err)
+	}
+
+	shortMessage, _ := fields["short_message"].(string)
+	if shortMessage == "" {
+		return models.LogEntry{}, errors.New("GELF message has no short_message")
+	}
+
+	entry := models.LogEntry{
+		Message: shortMessage,
+		Level:   "alert", // GELF's default level is 1
+		Context: make(models.Context),
+	}
+
+	for key, value := range fields {
+		switch key {
+		case "short_message", "version", "_id":
+		case "host":
+			entry.Host, _ = value.(string)
+		case "full_message", "facility", "file", "line":
+			entry.Context[key] = value
+		case "level":
+			if n, ok := value.(json.Number); ok {
+				if level, err := n.Int64(); err == nil {
+					entry.Level = syslog.SeverityLevel(int(level))
+				}
+			}
+		case "timestamp":
+			if n, ok := value.(json.Number); ok {
+				if seconds, err := n.Float64(); err == nil {
+					whole, frac := math.Modf(seconds)
+					entry.Timestamp = time.Unix(int64(whole), int64(math.Round(frac*1e6))*1e3).UTC()
+				}
+			}
+		default:
+			if strings.HasPrefix(key, "_") {
+				entry.Context[key[1:]] = value
+			}
+		}
+	}
+
+	return entry, nil
+}
+
+func decompress(payload []byte) ([]byte, error) {
+	var (
+		r   io.ReadCloser
+		err error
+	)
+	switch {
+	case len(payload) >= 2 && payload[0] == 0x1f && payload[1] == 0x8b:
+		r, err = gzip.NewReader(bytes.NewReader(payload))
+	case len(payload) >= 2 && payload[0] == 0x78:
+		r, err = zlib.NewReader(bytes.NewReader(payload))
+	default:
+		return payload, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create decompressor: %w", err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress GELF payload: %w", err)
+	}
+	if len(data) > maxDecompressedSize {
+		return nil, errors.New("decompressed GELF payload exceeds size limit")
+	}
+	return data, nil
+}
@@ -0,0 +1,74 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+)
+
+func compress(t *testing.T, payload string, newWriter func(io.Writer) io.WriteCloser) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := newWriter(&buf)
+	if _, err := io.WriteString(w, payload); err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	const payload = `{"version":"1.1","host":"web-1","short_message":"boom","full_message":"boom\nstack","timestamp":1709294400.123,"level":3,"_user_id":42,"_id":"dropped"}`
+	want := models.LogEntry{
+		Message:   "boom",
+		Host:      "web-1",
+		Level:     "error",
+		Timestamp: time.Date(2024, 3, 1, 12, 0, 0, 123000000, time.UTC),
+		Context:   models.Context{"full_message": "boom\nstack", "user_id": json.Number("42")},
+	}
+
+	tests := []struct {
+		name    string
+		payload []byte
+		want    models.LogEntry
+		wantErr bool
+	}{
+		{name: "plain", payload: []byte(payload), want: want},
+		{name: "gzip", payload: compress(t, payload, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }), want: want},
+		{name: "zlib", payload: compress(t, payload, func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }), want: want},
+		{
+			name:    "default level",
+			payload: []byte(`{"short_message":"x"}`),
+			want:    models.LogEntry{Message: "x", Level: "alert", Context: models.Context{}},
+		},
+		{name: "missing short_message", payload: []byte(`{"host":"a"}`), wantErr: true},
+		{name: "not JSON", payload: []byte(`nope`), wantErr: true},
+		{name: "corrupt gzip", payload: []byte{0x1f, 0x8b, 0x00}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.payload)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package gelf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	producer "github.com/aasheesh/logless/internal/kafka"
+)
+
+const (
+	maxUDPDatagramSize = 64 * 1024
+	maxTCPMessageSize  = 1 << 20
+)
+
+// Server receives GELF messages over UDP (optionally chunked and compressed)
+// and over null-byte delimited TCP, and publishes them through the Kafka
+// producer.
+type Server struct {
+	producer  *producer.LogProducer
+	assembler *assembler
+
+	mu      sync.Mutex
+	closers []io.Closer
+	conns   map[net.Conn]struct{}
+	wg      sync.WaitGroup
+	closed  bool
+}
+
+func NewServer(producer *producer.LogProducer) *Server {
+	return &Server{
+		producer:  producer,
+		assembler: newAssembler(),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+func (s *Server) ListenUDP(addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on udp %s: %w", addr, err)
+	}
+	if !s.track(conn) {
+		return net.ErrClosed
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		buf := make([]byte, maxUDPDatagramSize)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Printf("GELF UDP read error: %v", err)
+				}
+				return
+			}
+
+			payload := buf[:n]
+			if isChunked(payload) {
+				payload, err = s.assembler.add(payload, time.Now())
+				if err != nil {
+					log.Printf("Dropped GELF chunk: %v", err)
+					continue
+				}
+				if payload == nil {
+					continue
+				}
+			}
+			s.handle(payload)
+		}
+	}()
+
+	log.Printf("GELF listener ready on udp %s", addr)
+	return nil
+}
+
+func (s *Server) ListenTCP(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on tcp %s: %w", addr, err)
+	}
+	if !s.track(ln) {
+		return net.ErrClosed
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Printf("GELF TCP accept error: %v", err)
+				}
+				return
+			}
+
+			s.mu.Lock()
+			if s.closed {
+				s.mu.Unlock()
+				conn.Close()
+				return
+			}
+			s.conns[conn] = struct{}{}
+			s.wg.Add(1)
+			s.mu.Unlock()
+
+			go func() {
+				defer s.wg.Done()
+				s.handleConn(conn)
+
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+			}()
+		}
+	}()
+
+	log.Printf("GELF listener ready on tcp %s", addr)
+	return nil
+}
+
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for _, c := range s.closers {
+		c.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Server) track(c io.Closer) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		c.Close()
+		return false
+	}
+	s.closers = append(s.closers, c)
+	return true
+}
+
+// handleConn reads null-byte delimited frames until the client disconnects.
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		var frame []byte
+		for {
+			chunk, err := reader.ReadSlice(0)
+			frame = append(frame, chunk...)
+			if len(frame) > maxTCPMessageSize {
+				log.Printf("GELF connection from %s closed: message exceeds maximum size", conn.RemoteAddr())
+				return
+			}
+			if err == bufio.ErrBufferFull {
+				continue
+			}
+			if err != nil {
+				if len(frame) > 0 && err == io.EOF {
+					break
+				}
+				if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+					log.Printf("GELF connection from %s closed: %v", conn.RemoteAddr(), err)
+				}
+				return
+			}
+			break
+		}
+
+		if len(frame) > 0 && frame[len(frame)-1] == 0 {
+			frame = frame[:len(frame)-1]
+		}
+		if len(frame) > 0 {
+			s.handle(frame)
+		}
+	}
+}
+
+func (s *Server) handle(payload []byte) {
+	entry, err := Parse(payload)
+	if err != nil {
+		log.Printf("Failed to parse GELF message: %v", err)
+		return
+	}
+	if err := s.producer.SendLog(entry); err != nil {
+		log.Printf("Failed to send GELF message to Kafka: %v", err)
+	}
+}
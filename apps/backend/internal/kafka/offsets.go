@@ -0,0 +1,84 @@
+package producer
+
+import (
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+type partitionOffsets struct {
+	pending map[kafka.Offset]struct{}
+	// next is one past the highest offset read.
+	next      kafka.Offset
+	committed kafka.Offset
+}
+
+// OffsetTracker works out which offsets can be committed when messages
+// finish out of order. A partition only advances up to its oldest message
+// that is still pending, so nothing read but not yet stored is skipped after
+// a restart. It is not safe for concurrent use.
+type OffsetTracker struct {
+	partitions map[partitionKey]*partitionOffsets
+}
+
+func NewOffsetTracker() *OffsetTracker {
+	return &OffsetTracker{partitions: make(map[partitionKey]*partitionOffsets)}
+}
+
+// Read marks a message as pending.
+func (t *OffsetTracker) Read(tp kafka.TopicPartition) {
+	key := partitionKey{topic: *tp.Topic, partition: tp.Partition}
+	p, ok := t.partitions[key]
+	if !ok {
+		p = &partitionOffsets{pending: make(map[kafka.Offset]struct{}), committed: kafka.OffsetInvalid}
+		t.partitions[key] = p
+	}
+	p.pending[tp.Offset] = struct{}{}
+	if tp.Offset+1 > p.next {
+		p.next = tp.Offset + 1
+	}
+}
+
+// Done marks a message as handled: stored, or deliberately skipped.
+func (t *OffsetTracker) Done(tp kafka.TopicPartition) {
+	if p, ok := t.partitions[partitionKey{topic: *tp.Topic, partition: tp.Partition}]; ok {
+		delete(p.pending, tp.Offset)
+	}
+}
+
+// Committable returns, for each partition that advanced since the last
+// commit, the offset of the next message to consume.
+func (t *OffsetTracker) Committable() []kafka.TopicPartition {
+	var offsets []kafka.TopicPartition
+	for key, p := range t.partitions {
+		offset := p.next
+		for pending := range p.pending {
+			offset = min(offset, pending)
+		}
+		if offset != p.committed {
+			topic := key.topic
+			offsets = append(offsets, kafka.TopicPartition{Topic: &topic, Partition: key.partition, Offset: offset})
+		}
+	}
+	return offsets
+}
+
+// Committed records offsets the broker accepted.
+func (t *OffsetTracker) Committed(offsets []kafka.TopicPartition) {
+	for _, tp := range offsets {
+		if p, ok := t.partitions[partitionKey{topic: *tp.Topic, partition: tp.Partition}]; ok {
+			p.committed = tp.Offset
+		}
+	}
+}
+
+// Forget drops partitions that were revoked; messages from them that finish
+// later are ignored, and whoever owns them next re-reads them.
+func (t *OffsetTracker) Forget(partitions []kafka.TopicPartition) {
+	for _, tp := range partitions {
+		delete(t.partitions, partitionKey{topic: *tp.Topic, partition: tp.Partition})
+	}
+}
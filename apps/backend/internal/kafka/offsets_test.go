@@ -0,0 +1,120 @@
+package producer
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+func tp(topic string, partition int32, offset kafka.Offset) kafka.TopicPartition {
+	return kafka.TopicPartition{Topic: &topic, Partition: partition, Offset: offset}
+}
+
+// committable renders Committable as sorted "topic/partition@offset" strings.
+func committable(t *OffsetTracker) []string {
+	var out []string
+	for _, p := range t.Committable() {
+		out = append(out, fmt.Sprintf("%s/%d@%d", *p.Topic, p.Partition, p.Offset))
+	}
+	slices.Sort(out)
+	return out
+}
+
+func TestOffsetTracker(t *testing.T) {
+	type step struct {
+		read      []kafka.Offset
+		done      []kafka.Offset
+		commit    bool
+		forget    bool
+		wantAfter []string
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name:  "nothing done yet",
+			steps: []step{{read: []kafka.Offset{10, 11}, wantAfter: []string{"logs/0@10"}}},
+		},
+		{
+			name: "in order",
+			steps: []step{
+				{read: []kafka.Offset{10, 11, 12}, done: []kafka.Offset{10, 11}, wantAfter: []string{"logs/0@12"}},
+				{done: []kafka.Offset{12}, wantAfter: []string{"logs/0@13"}},
+			},
+		},
+		{
+			name: "out of order holds at the oldest pending",
+			steps: []step{
+				{read: []kafka.Offset{10, 11, 12}, done: []kafka.Offset{11, 12}, wantAfter: []string{"logs/0@10"}},
+				{done: []kafka.Offset{10}, wantAfter: []string{"logs/0@13"}},
+			},
+		},
+		{
+			name: "committed offsets are not returned again",
+			steps: []step{
+				{read: []kafka.Offset{5}, done: []kafka.Offset{5}, commit: true, wantAfter: nil},
+				{read: []kafka.Offset{6}, wantAfter: nil},
+				{done: []kafka.Offset{6}, wantAfter: []string{"logs/0@7"}},
+			},
+		},
+		{
+			name: "done for an unknown offset is harmless",
+			steps: []step{
+				{read: []kafka.Offset{1}, done: []kafka.Offset{99}, wantAfter: []string{"logs/0@1"}},
+			},
+		},
+		{
+			name: "revoked partition is forgotten",
+			steps: []step{
+				{read: []kafka.Offset{1, 2}, forget: true, wantAfter: nil},
+				{done: []kafka.Offset{1, 2}, wantAfter: nil},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewOffsetTracker()
+			for i, s := range tt.steps {
+				for _, offset := range s.read {
+					tracker.Read(tp("logs", 0, offset))
+				}
+				for _, offset := range s.done {
+					tracker.Done(tp("logs", 0, offset))
+				}
+				if s.forget {
+					tracker.Forget([]kafka.TopicPartition{tp("logs", 0, kafka.OffsetInvalid)})
+				}
+				if s.commit {
+					tracker.Committed(tracker.Committable())
+				}
+				if got := committable(tracker); !slices.Equal(got, s.wantAfter) {
+					t.Fatalf("step %d: committable = %v, want %v", i, got, s.wantAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestOffsetTrackerPartitions(t *testing.T) {
+	tracker := NewOffsetTracker()
+	tracker.Read(tp("logs", 0, 10))
+	tracker.Read(tp("logs", 1, 20))
+	tracker.Read(tp("other", 0, 30))
+	tracker.Done(tp("logs", 1, 20))
+	tracker.Done(tp("other", 0, 30))
+
+	want := []string{"logs/0@10", "logs/1@21", "other/0@31"}
+	if got := committable(tracker); !slices.Equal(got, want) {
+		t.Fatalf("committable = %v, want %v", got, want)
+	}
+
+	// Only the partitions the broker accepted stop being reported.
+	tracker.Committed([]kafka.TopicPartition{tp("logs", 1, 21)})
+	want = []string{"logs/0@10", "other/0@31"}
+	if got := committable(tracker); !slices.Equal(got, want) {
+		t.Errorf("after commit = %v, want %v", got, want)
+	}
+}
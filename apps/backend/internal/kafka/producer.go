@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/aasheesh/logless/internal/models"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
@@ -18,7 +19,7 @@ func NewLogProducer(kafka *kafka.Producer) *LogProducer {
 }
 
 func (lp *LogProducer) SendLog(logEntry models.LogEntry) error {
-	value, err := json.Marshal(logEntry)
+	value, err := encodeEntry(logEntry)
 	if err != nil {
 		log.Printf("Failed to marshal log entry to JSON: %v", err)
 		return err // Return the error if marshaling fails
@@ -42,6 +43,24 @@ func (lp *LogProducer) SendLog(logEntry models.LogEntry) error {
 	return nil
 }
 
+// encodeEntry stamps IngestedAt, replacing any value the client sent, and
+// encodes the entry for the logs topic. Every ingestion path goes through
+// SendLog, so the consumer can trust the stamp it decodes.
+func encodeEntry(logEntry models.LogEntry) ([]byte, error) {
+	logEntry.IngestedAt = time.Now().UTC()
+	return json.Marshal(logEntry)
+}
+
+// SendLogs enqueues every entry in a single pass and returns one error slot
+// per entry, nil for the entries that were enqueued.
+func (lp *LogProducer) SendLogs(logEntries []models.LogEntry) []error {
+	errs := make([]error, len(logEntries))
+	for i, logEntry := range logEntries {
+		errs[i] = lp.SendLog(logEntry)
+	}
+	return errs
+}
+
 func (lp *LogProducer) StartEventConsumer() {
 	go func() {
 		for e := range lp.kafka.Events() {
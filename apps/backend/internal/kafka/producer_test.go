@@ -0,0 +1,27 @@
+package producer
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+)
+
+func TestEncodeEntryStampsIngestedAt(t *testing.T) {
+	forged := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Now().UTC()
+	value, err := encodeEntry(models.LogEntry{Level: "info", Message: "hi", IngestedAt: forged})
+	if err != nil {
+		t.Fatalf("encodeEntry: %v", err)
+	}
+	after := time.Now().UTC()
+
+	var got models.LogEntry
+	if err := json.Unmarshal(value, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got.IngestedAt.Before(before) || got.IngestedAt.After(after) {
+		t.Errorf("ingested_at = %v, want between %v and %v", got.IngestedAt, before, after)
+	}
+}
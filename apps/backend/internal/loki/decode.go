@@ -0,0 +1,247 @@
+package loki
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/utils"
+	"github.com/klauspost/compress/snappy"
+)
+
+var ErrPayloadTooLarge = errors.New("decompressed payload exceeds size limit")
+
+type stream struct {
+	labels  map[string]string
+	entries []streamEntry
+}
+
+type streamEntry struct {
+	timestamp time.Time
+	line      string
+	metadata  map[string]string
+}
+
+// DecodeProtobuf converts a snappy-compressed logproto.PushRequest into log entries.
+func DecodeProtobuf(b []byte, maxSize int) ([]models.LogEntry, error) {
+	size, err := snappy.DecodedLen(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy payload: %w", err)
+	}
+	if size > maxSize {
+		return nil, ErrPayloadTooLarge
+	}
+	raw, err := snappy.Decode(nil, b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy payload: %w", err)
+	}
+
+	var streams []stream
+	err = utils.ReadProtoFields(raw, func(f utils.ProtoField) error {
+		if f.Number != 1 {
+			return nil
+		}
+		s, err := decodeStreamProto(f.Bytes)
+		if err != nil {
+			return err
+		}
+		streams = append(streams, s)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid push request: %w", err)
+	}
+	return toEntries(streams), nil
+}
+
+func decodeStreamProto(b []byte) (stream, error) {
+	var s stream
+	err := utils.ReadProtoFields(b, func(f utils.ProtoField) error {
+		switch f.Number {
+		case 1:
+			labels, err := ParseLabels(string(f.Bytes))
+			if err != nil {
+				return err
+			}
+			s.labels = labels
+		case 2:
+			var e streamEntry
+			err := utils.ReadProtoFields(f.Bytes, func(f utils.ProtoField) error {
+				switch f.Number {
+				case 1:
+					var seconds, nanos int64
+					err := utils.ReadProtoFields(f.Bytes, func(f utils.ProtoField) error {
+						switch f.Number {
+						case 1:
+							seconds = int64(f.Value)
+						case 2:
+							nanos = int64(int32(f.Value))
+						}
+						return nil
+					})
+					e.timestamp = time.Unix(seconds, nanos).UTC()
+					return err
+				case 2:
+					e.line = string(f.Bytes)
+				case 3:
+					var name, value string
+					err := utils.ReadProtoFields(f.Bytes, func(f utils.ProtoField) error {
+						switch f.Number {
+						case 1:
+							name = string(f.Bytes)
+						case 2:
+							value = string(f.Bytes)
+						}
+						return nil
+					})
+					if e.metadata == nil {
+						e.metadata = make(map[string]string)
+					}
+					e.metadata[name] = value
+					return err
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			s.entries = append(s.entries, e)
+		}
+		return nil
+	})
+	return s, err
+}
+
+type jsonPushRequest struct {
+	Streams []struct {
+		Stream map[string]string   `json:"stream"`
+		Values [][]json.RawMessage `json:"values"`
+	} `json:"streams"`
+}
+
+// DecodeJSON converts the JSON flavour of the push API into log entries. Each
+// value is [ "<unix epoch in nanoseconds>", "<line>", {structured metadata}? ].
+func DecodeJSON(b []byte) ([]models.LogEntry, error) {
+	var req jsonPushRequest
+	if err := json.Unmarshal(b, &req); err != nil {
+		return nil, fmt.Errorf("invalid push request: %w", err)
+	}
+
+	streams := make([]stream, 0, len(req.Streams))
+	for _, js := range req.Streams {
+		s := stream{labels: js.Stream}
+		for _, value := range js.Values {
+			if len(value) < 2 {
+				return nil, errors.New("invalid push request: each value needs a timestamp and a line")
+			}
+
+			var ts, line string
+			if err := json.Unmarshal(value[0], &ts); err != nil {
+				return nil, fmt.Errorf("invalid timestamp: %w", err)
+			}
+			nanos, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp %q: %w", ts, err)
+			}
+			if err := json.Unmarshal(value[1], &line); err != nil {
+				return nil, fmt.Errorf("invalid log line: %w", err)
+			}
+
+			e := streamEntry{timestamp: time.Unix(0, nanos).UTC(), line: line}
+			if len(value) > 2 {
+				if err := json.Unmarshal(value[2], &e.metadata); err != nil {
+					return nil, fmt.Errorf("invalid structured metadata: %w", err)
+				}
+			}
+			s.entries = append(s.entries, e)
+		}
+		streams = append(streams, s)
+	}
+	return toEntries(streams), nil
+}
+
+// toEntries copies the stream labels and any structured metadata into each
+// entry's context. The level is taken from a "level" or "detected_level"
+// label when the shipper provides one.
+func toEntries(streams []stream) []models.LogEntry {
+	var entries []models.LogEntry
+	for _, s := range streams {
+		for _, e := range s.entries {
+			context := make(models.Context, len(s.labels)+len(e.metadata))
+			for k, v := range s.labels {
+				context[k] = v
+			}
+			for k, v := range e.metadata {
+				context[k] = v
+			}
+
+			level := s.labels["level"]
+			if level == "" {
+				level = s.labels["detected_level"]
+			}
+			if metadataLevel := e.metadata["level"]; metadataLevel != "" {
+				level = metadataLevel
+			} else if level == "" {
+				level = e.metadata["detected_level"]
+			}
+
+			entry := models.LogEntry{
+				Level:     strings.ToLower(level),
+				Message:   e.line,
+				Timestamp: e.timestamp,
+			}
+			if len(context) > 0 {
+				entry.Context = context
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// ParseLabels parses a Prometheus-style label set such as
+// `{job="varlogs", filename="/var/log/syslog"}`.
+func ParseLabels(s string) (map[string]string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return nil, fmt.Errorf("invalid label set %q", s)
+	}
+	s = strings.TrimSpace(s[1 : len(s)-1])
+
+	labels := make(map[string]string)
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq < 1 {
+			return nil, fmt.Errorf("invalid label set near %q", s)
+		}
+		name := strings.TrimSpace(s[:eq])
+		s = strings.TrimSpace(s[eq+1:])
+
+		if !strings.HasPrefix(s, `"`) {
+			return nil, fmt.Errorf("label %q value must be quoted", name)
+		}
+		end := 1
+		for end < len(s) && s[end] != '"' {
+			if s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(s) {
+			return nil, fmt.Errorf("unterminated value for label %q", name)
+		}
+		value, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for label %q: %w", name, err)
+		}
+		labels[name] = value
+
+		s = strings.TrimSpace(s[end+1:])
+		s = strings.TrimSpace(strings.TrimPrefix(s, ","))
+	}
+	return labels, nil
+}
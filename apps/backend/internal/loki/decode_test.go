@@ -0,0 +1,183 @@
+package loki
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/klauspost/compress/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func bytesField(b []byte, num protowire.Number, value []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, value)
+}
+
+func varintField(b []byte, num protowire.Number, value uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, value)
+}
+
+func protoEntry(ts time.Time, line string, metadata ...[2]string) []byte {
+	timestamp := varintField(nil, 1, uint64(ts.Unix()))
+	timestamp = varintField(timestamp, 2, uint64(ts.Nanosecond()))
+
+	entry := bytesField(nil, 1, timestamp)
+	entry = bytesField(entry, 2, []byte(line))
+	for _, pair := range metadata {
+		label := bytesField(nil, 1, []byte(pair[0]))
+		label = bytesField(label, 2, []byte(pair[1]))
+		entry = bytesField(entry, 3, label)
+	}
+	return entry
+}
+
+func pushRequest(labels string, entries ...[]byte) []byte {
+	s := bytesField(nil, 1, []byte(labels))
+	for _, entry := range entries {
+		s = bytesField(s, 2, entry)
+	}
+	return snappy.Encode(nil, bytesField(nil, 1, s))
+}
+
+func TestDecodeProtobuf(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 500, time.UTC)
+
+	tests := []struct {
+		name    string
+		input   []byte
+		maxSize int
+		want    []models.LogEntry
+		wantErr error
+		anyErr  bool
+	}{
+		{
+			name: "labels and level",
+			input: pushRequest(`{job="api", level="WARN"}`,
+				protoEntry(ts, "slow request"),
+				protoEntry(ts.Add(time.Second), "done"),
+			),
+			maxSize: 1 << 20,
+			want: []models.LogEntry{
+				{Level: "warn", Message: "slow request", Timestamp: ts, Context: models.Context{"job": "api", "level": "WARN"}},
+				{Level: "warn", Message: "done", Timestamp: ts.Add(time.Second), Context: models.Context{"job": "api", "level": "WARN"}},
+			},
+		},
+		{
+			name:    "structured metadata level wins",
+			input:   pushRequest(`{detected_level="info"}`, protoEntry(ts, "boom", [2]string{"level", "error"}, [2]string{"trace_id", "abc"})),
+			maxSize: 1 << 20,
+			want: []models.LogEntry{
+				{Level: "error", Message: "boom", Timestamp: ts, Context: models.Context{"detected_level": "info", "level": "error", "trace_id": "abc"}},
+			},
+		},
+		{
+			name:    "over the size limit",
+			input:   pushRequest(`{}`, protoEntry(ts, "x")),
+			maxSize: 4,
+			wantErr: ErrPayloadTooLarge,
+		},
+		{name: "not snappy", input: []byte{0xff, 0xff, 0xff, 0xff, 0xff}, maxSize: 1 << 20, anyErr: true},
+		{name: "bad labels", input: pushRequest(`job="api"`, protoEntry(ts, "x")), maxSize: 1 << 20, anyErr: true},
+		{name: "truncated protobuf", input: snappy.Encode(nil, []byte{0x0a, 0x09, 0x0a}), maxSize: 1 << 20, anyErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeProtobuf(tt.input, tt.maxSize)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.anyErr:
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+			case err != nil:
+				t.Fatalf("DecodeProtobuf: %v", err)
+			case !reflect.DeepEqual(got, tt.want):
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []models.LogEntry
+		wantErr bool
+	}{
+		{
+			name:  "values with metadata",
+			input: `{"streams":[{"stream":{"app":"web"},"values":[["1709294400000000001","hello",{"detected_level":"debug"}],["1709294400000000002","bye"]]}]}`,
+			want: []models.LogEntry{
+				{Level: "debug", Message: "hello", Timestamp: time.Unix(0, 1709294400000000001).UTC(), Context: models.Context{"app": "web", "detected_level": "debug"}},
+				{Message: "bye", Timestamp: time.Unix(0, 1709294400000000002).UTC(), Context: models.Context{"app": "web"}},
+			},
+		},
+		{
+			name:  "no labels",
+			input: `{"streams":[{"values":[["1","x"]]}]}`,
+			want:  []models.LogEntry{{Message: "x", Timestamp: time.Unix(0, 1).UTC()}},
+		},
+		{name: "missing line", input: `{"streams":[{"values":[["1"]]}]}`, wantErr: true},
+		{name: "numeric timestamp", input: `{"streams":[{"values":[[1,"x"]]}]}`, wantErr: true},
+		{name: "bad timestamp", input: `{"streams":[{"values":[["soon","x"]]}]}`, wantErr: true},
+		{name: "bad metadata", input: `{"streams":[{"values":[["1","x",[]]]}]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeJSON([]byte(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeJSON: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{input: `{}`, want: map[string]string{}},
+		{input: `{job="varlogs", filename="/var/log/syslog"}`, want: map[string]string{"job": "varlogs", "filename": "/var/log/syslog"}},
+		{input: ` { a = "x\"y" ,b="\\" } `, want: map[string]string{"a": `x"y`, "b": `\`}},
+		{input: `{a="1",}`, want: map[string]string{"a": "1"}},
+		{input: `job="x"`, wantErr: true},
+		{input: `{job=x}`, wantErr: true},
+		{input: `{job="x}`, wantErr: true},
+		{input: `{="x"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLabels(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseLabels(%q) = %v, expected an error", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseLabels(%q): %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseLabels(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
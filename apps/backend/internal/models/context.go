@@ -0,0 +1,52 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Context holds the structured fields of a log entry. Values are strings,
+// booleans, numbers, arrays or nested objects as decoded from JSON. Numbers
+// are kept as json.Number so large integers survive the round trip through
+// Kafka and storage unchanged.
+type Context map[string]any
+
+func (c *Context) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var fields map[string]any
+	if err := decoder.Decode(&fields); err != nil {
+		return err
+	}
+	*c = fields
+	return nil
+}
+
+// Clone returns a deep copy of the context, so an entry can be promoted and
+// run through processors without changing other copies that share its maps.
+func (c Context) Clone() Context {
+	if c == nil {
+		return nil
+	}
+	return Context(cloneValue(map[string]any(c)).(map[string]any))
+}
+
+func cloneValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, child := range val {
+			out[k] = cloneValue(child)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, child := range val {
+			out[i] = cloneValue(child)
+		}
+		return out
+	default:
+		return v
+	}
+}
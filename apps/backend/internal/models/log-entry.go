@@ -1 +1,96 @@
-package models
\ No newline at end of file
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aasheesh/logless/internal/utils"
+)
+
+// Validate reports whether the entry is acceptable for ingestion.
+func (e LogEntry) Validate() error {
+	if e.Message == "" {
+		return errors.New("message cannot be empty")
+	}
+	return nil
+}
+
+// UnmarshalJSON accepts the timestamp formats understood by ParseTimestamp
+// instead of RFC 3339 only, and numeric levels such as pino's 30.
+func (e *LogEntry) UnmarshalJSON(data []byte) error {
+	type plain LogEntry
+	aux := struct {
+		*plain
+		Level     json.RawMessage `json:"level"`
+		Timestamp json.RawMessage `json:"timestamp"`
+	}{plain: (*plain)(e)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	level, err := parseLevel(aux.Level)
+	if err != nil {
+		return fmt.Errorf("invalid level: %w", err)
+	}
+	e.Level = level
+
+	ts, err := ParseTimestamp(aux.Timestamp)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+	e.Timestamp = ts
+	return nil
+}
+
+// parseLevel reads a level given as a string or a number; numbers are kept
+// as their decimal text for severity.Parse.
+func parseLevel(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	if raw[0] == '"' {
+		var level string
+		err := json.Unmarshal(raw, &level)
+		return level, err
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return "", err
+	}
+	return n.String(), nil
+}
+
+// fieldAliases lists the context keys, in order of preference, that clients
+// and shippers commonly use for each well-known field. ECS paths such as
+// host.name come before the bare key, which ECS uses for the whole object.
+var fieldAliases = []struct {
+	field func(e *LogEntry) *string
+	keys  []string
+}{
+	{func(e *LogEntry) *string { return &e.Service }, []string{"service.name", "service", "service_name", "app", "app_name"}},
+	{func(e *LogEntry) *string { return &e.Environment }, []string{"environment", "env", "deployment.environment", "deployment.environment.name"}},
+	{func(e *LogEntry) *string { return &e.Host }, []string{"host.name", "host", "hostname"}},
+	{func(e *LogEntry) *string { return &e.TraceID }, []string{"trace_id", "traceId", "trace.id", "traceid"}},
+	{func(e *LogEntry) *string { return &e.SpanID }, []string{"span_id", "spanId", "span.id", "spanid"}},
+	{func(e *LogEntry) *string { return &e.Source }, []string{"source", "log.file.path"}},
+}
+
+// PromoteFields fills the well-known fields that are still empty from their
+// usual context keys and removes those keys from the context. Trace and span
+// IDs are lower-cased so they match regardless of how the client spelled
+// the hex.
+func (e *LogEntry) PromoteFields() {
+	for _, alias := range fieldAliases {
+		field := alias.field(e)
+		if *field == "" && e.Context != nil {
+			*field = utils.TakeFirst(e.Context, alias.keys...)
+		}
+	}
+	e.TraceID = strings.ToLower(e.TraceID)
+	e.SpanID = strings.ToLower(e.SpanID)
+	if len(e.Context) == 0 {
+		e.Context = nil
+	}
+}
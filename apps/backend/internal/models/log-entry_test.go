@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestLogEntryUnmarshalLevel(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: `{"message":"m","level":"WARN"}`, want: "WARN"},
+		{input: `{"message":"m","level":30}`, want: "30"},
+		{input: `{"message":"m","level":3}`, want: "3"},
+		{input: `{"message":"m","level":null}`, want: ""},
+		{input: `{"message":"m"}`, want: ""},
+		{input: `{"message":"m","level":true}`, wantErr: true},
+		{input: `{"message":"m","level":{"name":"info"}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var entry LogEntry
+		err := json.Unmarshal([]byte(tt.input), &entry)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Unmarshal(%s) = %+v, expected an error", tt.input, entry)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.input, err)
+			continue
+		}
+		if entry.Level != tt.want {
+			t.Errorf("Unmarshal(%s) level = %q, want %q", tt.input, entry.Level, tt.want)
+		}
+	}
+}
+
+func TestPromoteFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry LogEntry
+		want  LogEntry
+	}{
+		{
+			name: "aliases",
+			entry: LogEntry{Context: Context{
+				"app": "api", "env": "prod", "hostname": "h1",
+				"traceId": "ABC", "span.id": "DEF", "log": map[string]any{"file": map[string]any{"path": "/var/log/a"}},
+			}},
+			want: LogEntry{Service: "api", Environment: "prod", Host: "h1", TraceID: "abc", SpanID: "def", Source: "/var/log/a"},
+		},
+		{
+			name: "ECS objects",
+			entry: LogEntry{Context: Context{
+				"service": map[string]any{"name": "api", "version": "1"},
+				"host":    map[string]any{"name": "h1"},
+			}},
+			want: LogEntry{Service: "api", Host: "h1", Context: Context{"service": map[string]any{"version": "1"}}},
+		},
+		{
+			name:  "set fields win",
+			entry: LogEntry{Service: "given", TraceID: "XYZ", Context: Context{"service": "other"}},
+			want:  LogEntry{Service: "given", TraceID: "xyz", Context: Context{"service": "other"}},
+		},
+		{
+			name:  "objects and arrays are not promoted",
+			entry: LogEntry{Context: Context{"host": map[string]any{"ip": "10.0.0.1"}, "env": []any{"a"}}},
+			want:  LogEntry{Context: Context{"host": map[string]any{"ip": "10.0.0.1"}, "env": []any{"a"}}},
+		},
+		{
+			name:  "numbers become strings",
+			entry: LogEntry{Context: Context{"service": json.Number("42")}},
+			want:  LogEntry{Service: "42"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.entry.PromoteFields()
+			if !reflect.DeepEqual(tt.entry, tt.want) {
+				t.Errorf("got %+v, want %+v", tt.entry, tt.want)
+			}
+		})
+	}
+}
@@ -1,12 +1,50 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/aasheesh/logless/internal/severity"
+)
 
 type LogEntry struct {
-	Level     string            `json:"level"`
-	Message   string            `json:"message"`
-	Timestamp time.Time         `json:"timestamp"`
-	Context   map[string]string `json:"context,omitempty"`
+	Level       string    `json:"level"`
+	Message     string    `json:"message"`
+	Timestamp   time.Time `json:"timestamp"`
+	Service     string    `json:"service,omitempty"`
+	Environment string    `json:"environment,omitempty"`
+	Host        string    `json:"host,omitempty"`
+	TraceID     string    `json:"trace_id,omitempty"`
+	SpanID      string    `json:"span_id,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	Context     Context   `json:"context,omitempty"`
+	// IngestedAt is set by the server when the entry is accepted, so clock
+	// skew between it and Timestamp is visible. Values sent by clients are
+	// overwritten.
+	IngestedAt time.Time `json:"ingested_at,omitzero"`
+	// RepeatCount, FirstSeen and LastSeen are set when deduplication
+	// collapsed a burst of identical entries into this one.
+	RepeatCount int       `json:"repeat_count,omitempty"`
+	FirstSeen   time.Time `json:"first_seen,omitzero"`
+	LastSeen    time.Time `json:"last_seen,omitzero"`
+}
+
+// LogFilter narrows log queries. Empty fields match everything.
+type LogFilter struct {
+	Service     string
+	Environment string
+	Host        string
+	TraceID     string
+	SpanID      string
+	Source      string
+	Level       severity.Range
+	// MinRepeatCount keeps only entries collapsed from at least this many
+	// repeats.
+	MinRepeatCount int
+	// Fields matches context values by dotted path, e.g. "status" or
+	// "http.method", compared as strings, numbers or booleans.
+	Fields map[string]string
+	Start  time.Time
+	End    time.Time
 }
 
 type ColorEntry struct {
@@ -25,4 +63,16 @@ type PaginatedLogsResponse struct {
 type HealthResponse struct {
 	Status  string `json:"status"`
 	Version string `json:"version"`
-}
\ No newline at end of file
+}
+
+type IngestResult struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BatchIngestResponse struct {
+	Accepted int            `json:"accepted"`
+	Rejected int            `json:"rejected"`
+	Results  []IngestResult `json:"results"`
+}
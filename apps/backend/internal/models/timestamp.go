@@ -0,0 +1,114 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timestampLayouts are tried in order after RFC 3339. Layouts without a zone
+// are read as UTC.
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05,999",         // log4j, Python logging
+	"02/Jan/2006:15:04:05 -0700",      // Apache and nginx access logs
+	"2006/01/02 15:04:05",             // nginx error log, Go's log package
+	"Mon Jan 02 15:04:05.999999 2006", // Apache error log
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.UnixDate,
+	time.ANSIC,
+}
+
+var (
+	customLayoutsMu sync.RWMutex
+	customLayouts   []string
+)
+
+// AddTimestampLayout registers a Go time layout that is tried before the
+// built-in ones, for sources with an unusual format.
+func AddTimestampLayout(layout string) {
+	customLayoutsMu.Lock()
+	defer customLayoutsMu.Unlock()
+	customLayouts = append(customLayouts, layout)
+}
+
+// ParseTimestamp decodes a JSON timestamp. Strings may use RFC 3339, a
+// registered custom layout, one of the common formats above or hold an epoch
+// number; numbers are epoch values whose unit is inferred from magnitude.
+// null and "" give the zero time.
+func ParseTimestamp(raw json.RawMessage) (time.Time, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return time.Time{}, nil
+	}
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp: %w", err)
+		}
+		return ParseTimestampString(s)
+	}
+	return parseEpoch(string(raw))
+}
+
+// ParseTimestampString is ParseTimestamp for values that are already strings.
+func ParseTimestampString(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return ts, nil
+	}
+
+	customLayoutsMu.RLock()
+	layouts := append(customLayouts[:len(customLayouts):len(customLayouts)], timestampLayouts...)
+	customLayoutsMu.RUnlock()
+
+	for _, layout := range layouts {
+		if ts, err := time.Parse(layout, s); err == nil {
+			return ts, nil
+		}
+	}
+	if ts, err := parseEpoch(s); err == nil {
+		return ts, nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized timestamp %q", s)
+}
+
+// parseEpoch reads seconds, milliseconds, microseconds or nanoseconds since
+// the Unix epoch. Values below 1e11 are seconds (until the year 5138) and
+// may carry a fraction; each further factor of 1000 selects the next unit.
+func parseEpoch(s string) (time.Time, error) {
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return time.Time{}, fmt.Errorf("unrecognized timestamp %q", s)
+	}
+
+	magnitude := math.Abs(value)
+	switch {
+	case magnitude < 1e11:
+		whole, frac := math.Modf(value)
+		return time.Unix(int64(whole), int64(math.Round(frac*1e9))).UTC(), nil
+	case magnitude < 1e14:
+		return time.UnixMicro(int64(math.Round(value * 1e3))).UTC(), nil
+	case magnitude < 1e17:
+		return time.UnixMicro(int64(math.Round(value))).UTC(), nil
+	}
+
+	// Nanoseconds exceed float64 precision, so parse integers exactly.
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(0, n).UTC(), nil
+	}
+	return time.Unix(0, int64(value)).UTC(), nil
+}
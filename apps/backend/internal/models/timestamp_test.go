@@ -0,0 +1,75 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		raw     string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "null", raw: `null`},
+		{name: "empty string", raw: `""`},
+		{name: "RFC 3339", raw: `"2024-03-01T12:00:00Z"`, want: want},
+		{name: "RFC 3339 with offset", raw: `"2024-03-01T14:00:00+02:00"`, want: want},
+		{name: "space separated with zone", raw: `"2024-03-01 12:00:00Z"`, want: want},
+		{name: "no zone is UTC", raw: `"2024-03-01T12:00:00.5"`, want: want.Add(500 * time.Millisecond)},
+		{name: "log4j comma", raw: `"2024-03-01 12:00:00,250"`, want: want.Add(250 * time.Millisecond)},
+		{name: "access log", raw: `"01/Mar/2024:13:00:00 +0100"`, want: want},
+		{name: "Go log", raw: `"2024/03/01 12:00:00"`, want: want},
+		{name: "Apache error log", raw: `"Fri Mar 01 12:00:00.000000 2024"`, want: want},
+		{name: "RFC 1123", raw: `"Fri, 01 Mar 2024 12:00:00 GMT"`, want: want},
+		{name: "epoch seconds", raw: `1709294400`, want: want},
+		{name: "fractional seconds", raw: `1709294400.25`, want: want.Add(250 * time.Millisecond)},
+		{name: "epoch milliseconds", raw: `1709294400123`, want: want.Add(123 * time.Millisecond)},
+		{name: "epoch microseconds", raw: `1709294400123456`, want: want.Add(123456 * time.Microsecond)},
+		{name: "epoch nanoseconds", raw: `1709294400123456789`, want: want.Add(123456789)},
+		{name: "quoted epoch", raw: `"1709294400"`, want: want},
+		{name: "zero", raw: `0`, want: time.Unix(0, 0).UTC()},
+		{name: "garbage", raw: `"yesterday"`, wantErr: true},
+		{name: "boolean", raw: `true`, wantErr: true},
+		{name: "malformed string", raw: `"abc`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTimestamp(json.RawMessage(tt.raw))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTimestamp(%s): %v", tt.raw, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTimestamp(%s) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddTimestampLayout(t *testing.T) {
+	const value = "01.03.2024 12:00:00"
+	if _, err := ParseTimestampString(value); err == nil {
+		t.Fatal("layout parsed before it was registered")
+	}
+
+	saved := customLayouts
+	t.Cleanup(func() { customLayouts = saved })
+	AddTimestampLayout("02.01.2006 15:04:05")
+
+	got, err := ParseTimestampString(value)
+	if err != nil {
+		t.Fatalf("ParseTimestampString: %v", err)
+	}
+	if want := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,162 @@
+// Package multiline joins continuation lines, such as the frames of a Java or
+// Python stack trace, into a single event before it is shipped.
+package multiline
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	// StartPattern matches the first line of an event. Lines that do not
+	// match it are treated as continuations.
+	StartPattern string
+	// ContinuePattern matches continuation lines. Lines that do not match it
+	// start a new event. When both patterns are set, a line matching
+	// StartPattern always starts a new event.
+	ContinuePattern string
+	// MaxLines caps the number of lines joined into one event.
+	MaxLines int
+	// FlushTimeout emits a buffered event when no line arrives for this long.
+	FlushTimeout time.Duration
+}
+
+// Enabled reports whether the config describes any multiline rule.
+func (c Config) Enabled() bool {
+	return c.StartPattern != "" || c.ContinuePattern != ""
+}
+
+type Rule struct {
+	start        *regexp.Regexp
+	continuation *regexp.Regexp
+	maxLines     int
+	flushTimeout time.Duration
+}
+
+func NewRule(config Config) (*Rule, error) {
+	if !config.Enabled() {
+		return nil, errors.New("multiline rule needs a start or continue pattern")
+	}
+
+	rule := &Rule{maxLines: config.MaxLines, flushTimeout: config.FlushTimeout}
+	if rule.maxLines <= 0 {
+		rule.maxLines = 500
+	}
+	if rule.flushTimeout <= 0 {
+		rule.flushTimeout = 2 * time.Second
+	}
+
+	var err error
+	if config.StartPattern != "" {
+		if rule.start, err = regexp.Compile(config.StartPattern); err != nil {
+			return nil, fmt.Errorf("invalid multiline start pattern: %w", err)
+		}
+	}
+	if config.ContinuePattern != "" {
+		if rule.continuation, err = regexp.Compile(config.ContinuePattern); err != nil {
+			return nil, fmt.Errorf("invalid multiline continue pattern: %w", err)
+		}
+	}
+	return rule, nil
+}
+
+func (r *Rule) isContinuation(line string) bool {
+	if r.start != nil && r.start.MatchString(line) {
+		return false
+	}
+	if r.continuation != nil {
+		return r.continuation.MatchString(line)
+	}
+	return true
+}
+
+// Line is one input line, or a joined event on output. Offset and Meta are
+// opaque to the assembler; a joined event keeps those of its first line, so a
+// caller can carry timestamps or checkpoint positions through.
+type Line struct {
+	Text   string
+	Offset int64
+	Meta   any
+}
+
+// Assembler buffers the lines of one stream. It is not safe for concurrent
+// use; each file or request gets its own.
+type Assembler struct {
+	rule     *Rule
+	first    Line
+	lines    []string
+	lastLine time.Time
+}
+
+func (r *Rule) NewAssembler() *Assembler {
+	return &Assembler{rule: r}
+}
+
+// Add feeds one line and returns the events it completed.
+func (a *Assembler) Add(line Line, now time.Time) []Line {
+	var events []Line
+
+	if len(a.lines) > 0 && !a.rule.isContinuation(line.Text) {
+		events = append(events, a.take())
+	}
+	if len(a.lines) == 0 {
+		a.first = line
+	}
+	a.lines = append(a.lines, line.Text)
+	a.lastLine = now
+
+	if len(a.lines) >= a.rule.maxLines {
+		events = append(events, a.take())
+	}
+	return events
+}
+
+// FlushExpired returns the buffered event once the flush timeout has passed
+// without a new line.
+func (a *Assembler) FlushExpired(now time.Time) (Line, bool) {
+	if len(a.lines) == 0 || now.Sub(a.lastLine) < a.rule.flushTimeout {
+		return Line{}, false
+	}
+	return a.take(), true
+}
+
+// Flush returns whatever is buffered regardless of the timeout.
+func (a *Assembler) Flush() (Line, bool) {
+	if len(a.lines) == 0 {
+		return Line{}, false
+	}
+	return a.take(), true
+}
+
+// Pending returns the offset of the first buffered line, if any, so a caller
+// can avoid checkpointing past lines that have not been emitted yet.
+func (a *Assembler) Pending() (int64, bool) {
+	return a.first.Offset, len(a.lines) > 0
+}
+
+func (a *Assembler) take() Line {
+	event := a.first
+	event.Text = strings.Join(a.lines, "\n")
+	a.lines = a.lines[:0]
+	a.first = Line{}
+	return event
+}
+
+// Join runs lines through a fresh assembler and returns the joined events. It
+// suits inputs that arrive complete, such as a request body.
+func (r *Rule) Join(lines []string) []string {
+	a := r.NewAssembler()
+	var events []string
+	for _, line := range lines {
+		for _, event := range a.Add(Line{Text: line}, time.Time{}) {
+			events = append(events, event.Text)
+		}
+	}
+	if event, ok := a.Flush(); ok {
+		events = append(events, event.Text)
+	}
+	return events
+}
@@ -0,0 +1,110 @@
+package multiline
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		lines  []string
+		want   []string
+	}{
+		{
+			name:   "start pattern",
+			config: Config{StartPattern: `^\d{4}-`},
+			lines:  []string{"2024-01-01 boom", "  at a", "  at b", "2024-01-01 next"},
+			want:   []string{"2024-01-01 boom\n  at a\n  at b", "2024-01-01 next"},
+		},
+		{
+			name:   "continue pattern",
+			config: Config{ContinuePattern: `^\s+at |^Caused by:`},
+			lines:  []string{"Exception", "  at a", "Caused by: x", "plain"},
+			want:   []string{"Exception\n  at a\nCaused by: x", "plain"},
+		},
+		{
+			name:   "start wins over continue",
+			config: Config{StartPattern: `^ERROR`, ContinuePattern: `.`},
+			lines:  []string{"ERROR one", "more", "ERROR two"},
+			want:   []string{"ERROR one\nmore", "ERROR two"},
+		},
+		{
+			name:   "leading continuation lines",
+			config: Config{StartPattern: `^START`},
+			lines:  []string{"orphan", "START", "x"},
+			want:   []string{"orphan", "START\nx"},
+		},
+		{
+			name:   "max lines",
+			config: Config{StartPattern: `^START`, MaxLines: 2},
+			lines:  []string{"START", "a", "b", "c"},
+			want:   []string{"START\na", "b\nc"},
+		},
+		{name: "empty input", config: Config{StartPattern: `x`}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := NewRule(tt.config)
+			if err != nil {
+				t.Fatalf("NewRule: %v", err)
+			}
+			if got := rule.Join(tt.lines); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRuleErrors(t *testing.T) {
+	for _, config := range []Config{
+		{},
+		{StartPattern: "("},
+		{ContinuePattern: "["},
+	} {
+		if _, err := NewRule(config); err == nil {
+			t.Errorf("NewRule(%+v) succeeded", config)
+		}
+	}
+}
+
+func TestAssembler(t *testing.T) {
+	rule, err := NewRule(Config{StartPattern: `^START`, FlushTimeout: time.Second})
+	if err != nil {
+		t.Fatalf("NewRule: %v", err)
+	}
+	a := rule.NewAssembler()
+	now := time.Now()
+
+	if _, ok := a.Pending(); ok {
+		t.Error("empty assembler reports a pending line")
+	}
+	a.Add(Line{Text: "START", Offset: 10, Meta: "first"}, now)
+	a.Add(Line{Text: "more", Offset: 16, Meta: "second"}, now)
+	if offset, ok := a.Pending(); !ok || offset != 10 {
+		t.Errorf("Pending = %d, %v, want 10, true", offset, ok)
+	}
+
+	if _, ok := a.FlushExpired(now.Add(time.Second / 2)); ok {
+		t.Error("flushed before the timeout")
+	}
+	event, ok := a.FlushExpired(now.Add(time.Second))
+	want := Line{Text: "START\nmore", Offset: 10, Meta: "first"}
+	if !ok || !reflect.DeepEqual(event, want) {
+		t.Errorf("FlushExpired = %+v, %v, want %+v", event, ok, want)
+	}
+	if _, ok := a.Flush(); ok {
+		t.Error("Flush returned an event after the buffer was emptied")
+	}
+
+	a.Add(Line{Text: "START", Offset: 30}, now)
+	events := a.Add(Line{Text: "START again", Offset: 40}, now)
+	if len(events) != 1 || events[0].Offset != 30 {
+		t.Errorf("Add = %+v, want the event at offset 30", events)
+	}
+	if offset, ok := a.Pending(); !ok || offset != 40 {
+		t.Errorf("Pending = %d, %v, want 40, true", offset, ok)
+	}
+}
@@ -0,0 +1,92 @@
+package otlp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/severity"
+)
+
+// DecodeProtobuf converts a protobuf-encoded ExportLogsServiceRequest into log entries.
+func DecodeProtobuf(b []byte) ([]models.LogEntry, error) {
+	var req exportLogsRequest
+	if err := decodeRequestProto(b, &req); err != nil {
+		return nil, fmt.Errorf("invalid OTLP protobuf payload: %w", err)
+	}
+	return req.entries(), nil
+}
+
+// DecodeJSON converts a JSON-encoded ExportLogsServiceRequest into log entries.
+func DecodeJSON(b []byte) ([]models.LogEntry, error) {
+	var req exportLogsRequest
+	if err := json.Unmarshal(b, &req); err != nil {
+		return nil, fmt.Errorf("invalid OTLP JSON payload: %w", err)
+	}
+	return req.entries(), nil
+}
+
+// entries flattens the resource/scope/record hierarchy. Attributes are merged
+// into the context with record attributes taking precedence over scope ones
+// and scope attributes over resource ones.
+func (req *exportLogsRequest) entries() []models.LogEntry {
+	var entries []models.LogEntry
+	for _, rl := range req.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			for _, lr := range sl.LogRecords {
+				context := make(models.Context)
+				addAttributes(context, rl.Resource.Attributes)
+				if sl.Scope.Name != "" {
+					context["otel.scope.name"] = sl.Scope.Name
+				}
+				if sl.Scope.Version != "" {
+					context["otel.scope.version"] = sl.Scope.Version
+				}
+				addAttributes(context, sl.Scope.Attributes)
+				addAttributes(context, lr.Attributes)
+
+				if lr.EventName != "" {
+					context["event.name"] = lr.EventName
+				}
+
+				entry := models.LogEntry{
+					Level:   severityLevel(lr.SeverityNumber, lr.SeverityText),
+					Message: lr.Body.String(),
+					TraceID: strings.ToLower(lr.TraceID),
+					SpanID:  strings.ToLower(lr.SpanID),
+				}
+				if lr.TimeUnixNano != 0 {
+					entry.Timestamp = time.Unix(0, int64(lr.TimeUnixNano)).UTC()
+				} else if lr.ObservedTimeUnixNano != 0 {
+					entry.Timestamp = time.Unix(0, int64(lr.ObservedTimeUnixNano)).UTC()
+				}
+				if len(context) > 0 {
+					entry.Context = context
+				}
+
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries
+}
+
+func addAttributes(context models.Context, attributes []keyValue) {
+	for i := range attributes {
+		context[attributes[i].Key] = attributes[i].Value.value()
+	}
+}
+
+// severityLevel prefers the SeverityText the SDK sent and falls back to the
+// SeverityNumber ranges defined by the OpenTelemetry log data model.
+func severityLevel(number int32, text string) string {
+	if text != "" {
+		return strings.ToLower(text)
+	}
+	if number < 1 {
+		return ""
+	}
+	return severity.FromOTel(number).String()
+}
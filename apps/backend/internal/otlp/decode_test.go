@@ -0,0 +1,194 @@
+package otlp
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func bytesField(b []byte, num protowire.Number, value []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, value)
+}
+
+func varintField(b []byte, num protowire.Number, value uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, value)
+}
+
+func fixed64Field(b []byte, num protowire.Number, value uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, value)
+}
+
+func stringAttribute(key, value string) []byte {
+	kv := bytesField(nil, 1, []byte(key))
+	return bytesField(kv, 2, bytesField(nil, 1, []byte(value)))
+}
+
+func attribute(key string, value []byte) []byte {
+	kv := bytesField(nil, 1, []byte(key))
+	return bytesField(kv, 2, value)
+}
+
+func request(records ...[]byte) []byte {
+	resource := bytesField(nil, 1, stringAttribute("service.name", "checkout"))
+
+	scope := bytesField(nil, 1, []byte("io.opentelemetry.log"))
+	scope = bytesField(scope, 2, []byte("1.2.0"))
+	scopeLogs := bytesField(nil, 1, scope)
+	for _, record := range records {
+		scopeLogs = bytesField(scopeLogs, 2, record)
+	}
+
+	resourceLogs := bytesField(nil, 1, resource)
+	resourceLogs = bytesField(resourceLogs, 2, scopeLogs)
+	return bytesField(nil, 1, resourceLogs)
+}
+
+func TestDecodeProtobuf(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	full := fixed64Field(nil, 1, uint64(ts.UnixNano()))
+	full = varintField(full, 2, 17)
+	full = bytesField(full, 5, bytesField(nil, 1, []byte("payment failed")))
+	full = bytesField(full, 6, attribute("attempt", varintField(nil, 3, 3)))
+	full = bytesField(full, 6, attribute("retry", varintField(nil, 2, 1)))
+	full = bytesField(full, 6, attribute("ratio", fixed64Field(nil, 4, math.Float64bits(0.5))))
+	full = bytesField(full, 9, []byte{0x5B, 0x8E, 0xFF, 0xF7, 0x98, 0x03, 0x81, 0x03, 0xD2, 0x69, 0xB6, 0x33, 0x81, 0x3F, 0xC6, 0x0C})
+	full = bytesField(full, 10, []byte{0xEE, 0xE1, 0x9B, 0x7E, 0xC3, 0xC1, 0xB1, 0x74})
+	full = bytesField(full, 12, []byte("payment.failed"))
+
+	array := bytesField(nil, 1, bytesField(nil, 1, []byte("a")))
+	array = bytesField(array, 1, varintField(nil, 3, 2))
+	kvlist := bytesField(nil, 1, stringAttribute("user", "42"))
+	composite := varintField(nil, 11, uint64(ts.UnixNano()))
+	composite = bytesField(composite, 3, []byte("WARNING"))
+	composite = bytesField(composite, 5, bytesField(nil, 6, kvlist))
+	composite = bytesField(composite, 6, attribute("tags", bytesField(nil, 5, array)))
+	composite = bytesField(composite, 6, attribute("service.name", bytesField(nil, 1, []byte("override"))))
+
+	// A recent SDK field this decoder does not know about is skipped.
+	unknown := bytesField(nil, 5, bytesField(nil, 1, []byte("x")))
+	unknown = bytesField(unknown, 99, []byte("ignored"))
+
+	entries, err := DecodeProtobuf(request(full, composite, unknown))
+	if err != nil {
+		t.Fatalf("DecodeProtobuf: %v", err)
+	}
+
+	scopeContext := func(extra models.Context) models.Context {
+		context := models.Context{
+			"service.name":       "checkout",
+			"otel.scope.name":    "io.opentelemetry.log",
+			"otel.scope.version": "1.2.0",
+		}
+		for key, value := range extra {
+			context[key] = value
+		}
+		return context
+	}
+	want := []models.LogEntry{
+		{
+			Level:     "error",
+			Message:   "payment failed",
+			Timestamp: ts,
+			TraceID:   "5b8efff798038103d269b633813fc60c",
+			SpanID:    "eee19b7ec3c1b174",
+			Context: scopeContext(models.Context{
+				"attempt":    int64(3),
+				"retry":      true,
+				"ratio":      0.5,
+				"event.name": "payment.failed",
+			}),
+		},
+		{
+			Level:     "warning",
+			Message:   `{"user":"42"}`,
+			Timestamp: ts,
+			Context: scopeContext(models.Context{
+				"tags":         []any{"a", int64(2)},
+				"service.name": "override",
+			}),
+		},
+		{
+			Message: "x",
+			Context: scopeContext(nil),
+		},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("got  %+v\nwant %+v", entries, want)
+	}
+}
+
+func TestDecodeProtobufErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{name: "truncated length", input: []byte{0x0a, 0x05, 0x01}},
+		{name: "truncated nested record", input: request([]byte{0x2a, 0x10})},
+		{name: "invalid tag", input: []byte{0x00}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if entries, err := DecodeProtobuf(tt.input); err == nil {
+				t.Errorf("expected an error, got %+v", entries)
+			}
+		})
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []models.LogEntry
+		wantErr bool
+	}{
+		{
+			name: "quoted and plain 64-bit integers",
+			input: `{"resourceLogs":[{"scopeLogs":[{"logRecords":[
+				{"timeUnixNano":"1709294400000000000","severityNumber":9,"body":{"stringValue":"hi"},
+				 "attributes":[{"key":"n","value":{"intValue":"7"}},{"key":"m","value":{"intValue":8}}],
+				 "traceId":"5B8EFFF798038103D269B633813FC60C"}]}]}]}`,
+			want: []models.LogEntry{{
+				Level:     "info",
+				Message:   "hi",
+				Timestamp: time.Unix(0, 1709294400000000000).UTC(),
+				TraceID:   "5b8efff798038103d269b633813fc60c",
+				Context:   models.Context{"n": int64(7), "m": int64(8)},
+			}},
+		},
+		{
+			name: "observed time and severity text",
+			input: `{"resourceLogs":[{"scopeLogs":[{"logRecords":[
+				{"observedTimeUnixNano":1000,"severityText":"DEBUG","body":{"boolValue":true}}]}]}]}`,
+			want: []models.LogEntry{{Level: "debug", Message: "true", Timestamp: time.Unix(0, 1000).UTC()}},
+		},
+		{name: "empty request", input: `{}`, want: nil},
+		{name: "bad integer", input: `{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"timeUnixNano":"soon"}]}]}]}`, wantErr: true},
+		{name: "not JSON", input: `nope`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeJSON([]byte(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeJSON: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package otlp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// The types below mirror the subset of opentelemetry/proto/logs/v1 that
+// LogLess maps onto a LogEntry. Field names follow the OTLP/JSON encoding;
+// the protobuf decoder fills the same structs.
+
+type exportLogsRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeLogs struct {
+	Scope      instrumentationScope `json:"scope"`
+	LogRecords []logRecord          `json:"logRecords"`
+}
+
+type instrumentationScope struct {
+	Name       string     `json:"name"`
+	Version    string     `json:"version"`
+	Attributes []keyValue `json:"attributes"`
+}
+
+type logRecord struct {
+	TimeUnixNano         jsonUint64 `json:"timeUnixNano"`
+	ObservedTimeUnixNano jsonUint64 `json:"observedTimeUnixNano"`
+	SeverityNumber       int32      `json:"severityNumber"`
+	SeverityText         string     `json:"severityText"`
+	Body                 *anyValue  `json:"body"`
+	Attributes           []keyValue `json:"attributes"`
+	TraceID              string     `json:"traceId"`
+	SpanID               string     `json:"spanId"`
+	EventName            string     `json:"eventName"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string       `json:"stringValue,omitempty"`
+	BoolValue   *bool         `json:"boolValue,omitempty"`
+	IntValue    *jsonInt64    `json:"intValue,omitempty"`
+	DoubleValue *float64      `json:"doubleValue,omitempty"`
+	ArrayValue  *arrayValue   `json:"arrayValue,omitempty"`
+	KvlistValue *keyValueList `json:"kvlistValue,omitempty"`
+	BytesValue  []byte        `json:"bytesValue,omitempty"`
+}
+
+type arrayValue struct {
+	Values []anyValue `json:"values"`
+}
+
+type keyValueList struct {
+	Values []keyValue `json:"values"`
+}
+
+// jsonInt64 and jsonUint64 accept both the quoted form proto3 JSON uses for
+// 64-bit integers and plain numbers, which several SDKs emit instead.
+type jsonInt64 int64
+
+func (v *jsonInt64) UnmarshalJSON(b []byte) error {
+	n, err := strconv.ParseInt(strings.Trim(string(b), `"`), 10, 64)
+	if err != nil {
+		return err
+	}
+	*v = jsonInt64(n)
+	return nil
+}
+
+type jsonUint64 uint64
+
+func (v *jsonUint64) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*v = jsonUint64(n)
+	return nil
+}
+
+// value converts the AnyValue into plain Go values.
+func (v *anyValue) value() any {
+	switch {
+	case v == nil:
+		return nil
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.IntValue != nil:
+		return int64(*v.IntValue)
+	case v.DoubleValue != nil:
+		return *v.DoubleValue
+	case v.ArrayValue != nil:
+		values := make([]any, len(v.ArrayValue.Values))
+		for i := range v.ArrayValue.Values {
+			values[i] = v.ArrayValue.Values[i].value()
+		}
+		return values
+	case v.KvlistValue != nil:
+		values := make(map[string]any, len(v.KvlistValue.Values))
+		for i := range v.KvlistValue.Values {
+			values[v.KvlistValue.Values[i].Key] = v.KvlistValue.Values[i].Value.value()
+		}
+		return values
+	case v.BytesValue != nil:
+		return base64.StdEncoding.EncodeToString(v.BytesValue)
+	}
+	return nil
+}
+
+// String renders the AnyValue for the string-only LogEntry fields. Composite
+// values are encoded as JSON.
+func (v *anyValue) String() string {
+	switch val := v.value().(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case bool:
+		return strconv.FormatBool(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	default:
+		data, _ := json.Marshal(val)
+		return string(data)
+	}
+}
@@ -0,0 +1,156 @@
+package otlp
+
+import (
+	"encoding/hex"
+	"math"
+
+	"github.com/aasheesh/logless/internal/utils"
+)
+
+func decodeRequestProto(b []byte, req *exportLogsRequest) error {
+	return utils.ReadProtoFields(b, func(f utils.ProtoField) error {
+		if f.Number == 1 && f.Bytes != nil {
+			var rl resourceLogs
+			if err := decodeResourceLogsProto(f.Bytes, &rl); err != nil {
+				return err
+			}
+			req.ResourceLogs = append(req.ResourceLogs, rl)
+		}
+		return nil
+	})
+}
+
+func decodeResourceLogsProto(b []byte, rl *resourceLogs) error {
+	return utils.ReadProtoFields(b, func(f utils.ProtoField) error {
+		switch f.Number {
+		case 1:
+			return utils.ReadProtoFields(f.Bytes, func(f utils.ProtoField) error {
+				if f.Number == 1 {
+					return appendKeyValueProto(f.Bytes, &rl.Resource.Attributes)
+				}
+				return nil
+			})
+		case 2:
+			var sl scopeLogs
+			if err := decodeScopeLogsProto(f.Bytes, &sl); err != nil {
+				return err
+			}
+			rl.ScopeLogs = append(rl.ScopeLogs, sl)
+		}
+		return nil
+	})
+}
+
+func decodeScopeLogsProto(b []byte, sl *scopeLogs) error {
+	return utils.ReadProtoFields(b, func(f utils.ProtoField) error {
+		switch f.Number {
+		case 1:
+			return utils.ReadProtoFields(f.Bytes, func(f utils.ProtoField) error {
+				switch f.Number {
+				case 1:
+					sl.Scope.Name = string(f.Bytes)
+				case 2:
+					sl.Scope.Version = string(f.Bytes)
+				case 3:
+					return appendKeyValueProto(f.Bytes, &sl.Scope.Attributes)
+				}
+				return nil
+			})
+		case 2:
+			var lr logRecord
+			if err := decodeLogRecordProto(f.Bytes, &lr); err != nil {
+				return err
+			}
+			sl.LogRecords = append(sl.LogRecords, lr)
+		}
+		return nil
+	})
+}
+
+func decodeLogRecordProto(b []byte, lr *logRecord) error {
+	return utils.ReadProtoFields(b, func(f utils.ProtoField) error {
+		switch f.Number {
+		case 1:
+			lr.TimeUnixNano = jsonUint64(f.Value)
+		case 2:
+			lr.SeverityNumber = int32(f.Value)
+		case 3:
+			lr.SeverityText = string(f.Bytes)
+		case 5:
+			lr.Body = &anyValue{}
+			return decodeAnyValueProto(f.Bytes, lr.Body)
+		case 6:
+			return appendKeyValueProto(f.Bytes, &lr.Attributes)
+		case 9:
+			lr.TraceID = hex.EncodeToString(f.Bytes)
+		case 10:
+			lr.SpanID = hex.EncodeToString(f.Bytes)
+		case 11:
+			lr.ObservedTimeUnixNano = jsonUint64(f.Value)
+		case 12:
+			lr.EventName = string(f.Bytes)
+		}
+		return nil
+	})
+}
+
+func appendKeyValueProto(b []byte, kvs *[]keyValue) error {
+	var kv keyValue
+	err := utils.ReadProtoFields(b, func(f utils.ProtoField) error {
+		switch f.Number {
+		case 1:
+			kv.Key = string(f.Bytes)
+		case 2:
+			return decodeAnyValueProto(f.Bytes, &kv.Value)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	*kvs = append(*kvs, kv)
+	return nil
+}
+
+func decodeAnyValueProto(b []byte, v *anyValue) error {
+	return utils.ReadProtoFields(b, func(f utils.ProtoField) error {
+		switch f.Number {
+		case 1:
+			s := string(f.Bytes)
+			v.StringValue = &s
+		case 2:
+			b := f.Value != 0
+			v.BoolValue = &b
+		case 3:
+			i := jsonInt64(int64(f.Value))
+			v.IntValue = &i
+		case 4:
+			d := math.Float64frombits(f.Value)
+			v.DoubleValue = &d
+		case 5:
+			v.ArrayValue = &arrayValue{}
+			return utils.ReadProtoFields(f.Bytes, func(f utils.ProtoField) error {
+				if f.Number != 1 {
+					return nil
+				}
+				var item anyValue
+				if err := decodeAnyValueProto(f.Bytes, &item); err != nil {
+					return err
+				}
+				v.ArrayValue.Values = append(v.ArrayValue.Values, item)
+				return nil
+			})
+		case 6:
+			v.KvlistValue = &keyValueList{}
+			return utils.ReadProtoFields(f.Bytes, func(f utils.ProtoField) error {
+				if f.Number == 1 {
+					return appendKeyValueProto(f.Bytes, &v.KvlistValue.Values)
+				}
+				return nil
+			})
+		case 7:
+			v.BytesValue = append([]byte{}, f.Bytes...)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,81 @@
+package pipeline
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/severity"
+	"github.com/aasheesh/logless/internal/utils"
+)
+
+// condition matches entries whose fields all match. Each field lists the
+// values it accepts; level accepts severity conditions such as ">=warn".
+type condition []fieldMatch
+
+type fieldMatch struct {
+	field  field
+	values []string
+	levels []severity.Range
+}
+
+func newCondition(spec map[string]any) (condition, error) {
+	var c condition
+	for name, value := range spec {
+		f, err := parseField(name)
+		if err != nil {
+			return nil, err
+		}
+
+		values, ok := value.([]any)
+		if !ok {
+			values = []any{value}
+		}
+
+		m := fieldMatch{field: f}
+		for _, v := range values {
+			s := utils.JSONString(v)
+			if name == "level" {
+				r, err := severity.ParseCondition(s)
+				if err != nil {
+					return nil, fmt.Errorf("level: %w", err)
+				}
+				m.levels = append(m.levels, r)
+				continue
+			}
+			m.values = append(m.values, s)
+		}
+		c = append(c, m)
+	}
+	return c, nil
+}
+
+func (c condition) matches(e *models.LogEntry) bool {
+	for _, m := range c {
+		if !m.matches(e) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m fieldMatch) matches(e *models.LogEntry) bool {
+	if m.levels != nil {
+		level, ok := severity.Parse(e.Level)
+		if !ok {
+			return false
+		}
+		for _, r := range m.levels {
+			if r.Contains(level) {
+				return true
+			}
+		}
+		return false
+	}
+
+	value, ok := m.field.get(e)
+	if !ok {
+		return false
+	}
+	return slices.Contains(m.values, utils.JSONString(value))
+}
@@ -0,0 +1,93 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/utils"
+)
+
+var entryFields = map[string]func(e *models.LogEntry) *string{
+	"level":       func(e *models.LogEntry) *string { return &e.Level },
+	"message":     func(e *models.LogEntry) *string { return &e.Message },
+	"service":     func(e *models.LogEntry) *string { return &e.Service },
+	"environment": func(e *models.LogEntry) *string { return &e.Environment },
+	"host":        func(e *models.LogEntry) *string { return &e.Host },
+	"trace_id":    func(e *models.LogEntry) *string { return &e.TraceID },
+	"span_id":     func(e *models.LogEntry) *string { return &e.SpanID },
+	"source":      func(e *models.LogEntry) *string { return &e.Source },
+}
+
+// field addresses either one of the entry's string fields or a dotted path
+// inside its context, written "context.user.id".
+type field struct {
+	name  string
+	entry func(e *models.LogEntry) *string
+	path  string
+}
+
+func parseField(name string) (field, error) {
+	if entry, ok := entryFields[name]; ok {
+		return field{name: name, entry: entry}, nil
+	}
+	if path, ok := strings.CutPrefix(name, "context."); ok && path != "" {
+		return field{name: name, path: path}, nil
+	}
+	return field{}, fmt.Errorf("unknown field %q, expected an entry field or context.<key>", name)
+}
+
+// captureField resolves a name written by an extraction processor: entry
+// fields by name, anything else as a context key.
+func captureField(name string) field {
+	if f, err := parseField(name); err == nil {
+		return f
+	}
+	return field{name: name, path: name}
+}
+
+func parseFields(names []string) ([]field, error) {
+	fields := make([]field, 0, len(names))
+	for _, name := range names {
+		f, err := parseField(name)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// get returns the field value; empty entry fields count as missing.
+func (f field) get(e *models.LogEntry) (any, bool) {
+	if f.entry != nil {
+		value := *f.entry(e)
+		return value, value != ""
+	}
+	if e.Context == nil {
+		return nil, false
+	}
+	return utils.LookupPath(e.Context, f.path)
+}
+
+// set stores value, rendering it as a string for entry fields.
+func (f field) set(e *models.LogEntry, value any) {
+	if f.entry != nil {
+		*f.entry(e) = utils.JSONString(value)
+		return
+	}
+	if e.Context == nil {
+		e.Context = make(models.Context)
+	}
+	utils.SetPath(e.Context, f.path, value)
+}
+
+func (f field) remove(e *models.LogEntry) {
+	if f.entry != nil {
+		*f.entry(e) = ""
+		return
+	}
+	if e.Context != nil {
+		utils.DeletePath(e.Context, f.path)
+	}
+}
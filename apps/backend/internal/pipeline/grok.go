@@ -0,0 +1,214 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/utils"
+)
+
+// grokReference matches %{PATTERN}, %{PATTERN:field} and %{PATTERN:field:type}.
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::([\w.@-]+))?(?::(\w+))?\}`)
+
+// maxGrokDepth bounds pattern expansion so self-referencing definitions fail
+// instead of recursing forever.
+const maxGrokDepth = 32
+
+type grokCapture struct {
+	field string
+	kind  string
+	group int
+}
+
+type grokPattern struct {
+	re       *regexp.Regexp
+	captures []grokCapture
+}
+
+// compileGrok expands the pattern references into a regular expression.
+// Named references become capture groups; the group names are generated
+// because field names such as "http.status" are not valid in Go regexps.
+func compileGrok(pattern string, definitions map[string]string) (*grokPattern, error) {
+	g := &grokPattern{}
+	expanded, err := g.expand(pattern, definitions, 0)
+	if err != nil {
+		return nil, err
+	}
+	if g.re, err = regexp.Compile(expanded); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	for i := range g.captures {
+		g.captures[i].group = g.re.SubexpIndex(fmt.Sprintf("g%d", i))
+	}
+	return g, nil
+}
+
+func (g *grokPattern) expand(pattern string, definitions map[string]string, depth int) (string, error) {
+	if depth > maxGrokDepth {
+		return "", errors.New("grok patterns nest too deeply, check for a pattern referencing itself")
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range grokReference.FindAllStringSubmatchIndex(pattern, -1) {
+		b.WriteString(pattern[last:m[0]])
+		last = m[1]
+
+		name := pattern[m[2]:m[3]]
+		definition, ok := definitions[name]
+		if !ok {
+			return "", fmt.Errorf("unknown grok pattern %q", name)
+		}
+		inner, err := g.expand(definition, definitions, depth+1)
+		if err != nil {
+			return "", err
+		}
+		if m[4] < 0 {
+			b.WriteString("(?:" + inner + ")")
+			continue
+		}
+
+		capture := grokCapture{field: pattern[m[4]:m[5]]}
+		if m[6] >= 0 {
+			capture.kind = pattern[m[6]:m[7]]
+			if capture.kind != "int" && capture.kind != "float" {
+				return "", fmt.Errorf("unknown type %q for %s, expected int or float", capture.kind, capture.field)
+			}
+		}
+		fmt.Fprintf(&b, "(?P<g%d>%s)", len(g.captures), inner)
+		g.captures = append(g.captures, capture)
+	}
+	b.WriteString(pattern[last:])
+	return b.String(), nil
+}
+
+// match returns the non-empty captures by field name, converted to their
+// type where one was given and the value parses.
+func (g *grokPattern) match(s string) (map[string]any, bool) {
+	loc := g.re.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return nil, false
+	}
+
+	fields := make(map[string]any, len(g.captures))
+	for _, c := range g.captures {
+		start, end := loc[2*c.group], loc[2*c.group+1]
+		if start < 0 || start == end {
+			continue
+		}
+		value := s[start:end]
+		switch c.kind {
+		case "int":
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				fields[c.field] = n
+				continue
+			}
+		case "float":
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				fields[c.field] = f
+				continue
+			}
+		}
+		fields[c.field] = value
+	}
+	return fields, true
+}
+
+// grok extracts named captures from a string field. Patterns are tried in
+// order and the first that matches wins; captures named after an entry field
+// such as level set that field, the rest go into the context.
+type grok struct {
+	field    field
+	prefix   string
+	patterns []*grokPattern
+	target   *field
+}
+
+func newGrok(config ProcessorConfig) (processor, error) {
+	var options struct {
+		Field    string   `yaml:"field"`
+		Patterns []string `yaml:"patterns"`
+		// PatternDefinitions adds or overrides named patterns for this
+		// processor only.
+		PatternDefinitions map[string]string `yaml:"pattern_definitions"`
+		// Prefix skips values that do not start with it, which is cheaper
+		// than letting every pattern fail.
+		Prefix string `yaml:"prefix"`
+		Target string `yaml:"target"`
+	}
+	if err := config.decodeOptions(&options); err != nil {
+		return nil, err
+	}
+	if len(options.Patterns) == 0 {
+		return nil, errors.New("patterns is required")
+	}
+	if options.Field == "" {
+		options.Field = "message"
+	}
+
+	f, err := parseField(options.Field)
+	if err != nil {
+		return nil, err
+	}
+	p := &grok{field: f, prefix: options.Prefix}
+	if options.Target != "" && options.Target != "context" {
+		target, err := parseField(options.Target)
+		if err != nil || target.entry != nil {
+			return nil, fmt.Errorf("target %q must be context or context.<key>", options.Target)
+		}
+		p.target = &target
+	}
+
+	definitions := make(map[string]string, len(builtinGrokPatterns)+len(config.grokPatterns)+len(options.PatternDefinitions))
+	for _, source := range []map[string]string{builtinGrokPatterns, config.grokPatterns, options.PatternDefinitions} {
+		for name, definition := range source {
+			definitions[name] = definition
+		}
+	}
+	for _, pattern := range options.Patterns {
+		compiled, err := compileGrok(pattern, definitions)
+		if err != nil {
+			return nil, err
+		}
+		p.patterns = append(p.patterns, compiled)
+	}
+	return p, nil
+}
+
+func (p *grok) process(entry *models.LogEntry) (bool, error) {
+	value, ok := p.field.get(entry)
+	if !ok {
+		return true, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return true, fmt.Errorf("%s is not a string", p.field.name)
+	}
+	if !strings.HasPrefix(s, p.prefix) {
+		return true, nil
+	}
+
+	for _, pattern := range p.patterns {
+		fields, ok := pattern.match(s)
+		if !ok {
+			continue
+		}
+		if p.target != nil {
+			object := make(map[string]any, len(fields))
+			for name, v := range fields {
+				utils.SetPath(object, name, v)
+			}
+			p.target.set(entry, object)
+			return true, nil
+		}
+		for name, v := range fields {
+			captureField(name).set(entry, v)
+		}
+		return true, nil
+	}
+	return true, fmt.Errorf("no grok pattern matched %s", p.field.name)
+}
@@ -0,0 +1,219 @@
+package pipeline
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aasheesh/logless/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCompileGrok(t *testing.T) {
+	definitions := map[string]string{
+		"NUM":   `[0-9]+`,
+		"PAIR":  `%{NUM:left:int}-%{NUM:right}`,
+		"LOOP":  `%{LOOP}`,
+		"OUTER": `<%{PAIR}>`,
+	}
+	tests := []struct {
+		name    string
+		pattern string
+		input   string
+		want    map[string]any
+		noMatch bool
+		wantErr bool
+	}{
+		{name: "plain reference", pattern: `id=%{NUM}`, input: "id=7", want: map[string]any{}},
+		{name: "named and typed", pattern: `%{NUM:n:int} %{NUM:f:float} %{NUM:s}`, input: "1 2 3", want: map[string]any{"n": int64(1), "f": 2.0, "s": "3"}},
+		{name: "nested definitions keep their captures", pattern: `%{OUTER}`, input: "<4-5>", want: map[string]any{"left": int64(4), "right": "5"}},
+		{name: "dotted field name", pattern: `%{NUM:http.status:int}`, input: "404", want: map[string]any{"http.status": int64(404)}},
+		{name: "empty capture is skipped", pattern: `a(%{NUM:n})?b`, input: "ab", want: map[string]any{}},
+		{name: "no match", pattern: `%{NUM:n}`, input: "abc", noMatch: true},
+		{name: "unknown pattern", pattern: `%{NOPE}`, wantErr: true},
+		{name: "unknown type", pattern: `%{NUM:n:bool}`, wantErr: true},
+		{name: "self reference", pattern: `%{LOOP}`, wantErr: true},
+		{name: "invalid regexp", pattern: `(%{NUM}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := compileGrok(tt.pattern, definitions)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("compileGrok: %v", err)
+			}
+			got, ok := g.match(tt.input)
+			if ok == tt.noMatch {
+				t.Fatalf("match(%q) ok = %v", tt.input, ok)
+			}
+			if !tt.noMatch && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("match(%q) = %#v, want %#v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuiltinGrokPatterns(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		want    map[string]any
+	}{
+		{
+			pattern: "%{NGINXACCESS}",
+			input:   `203.0.113.9 - alice [01/Mar/2024:12:00:00 +0000] "GET /cart?id=1 HTTP/1.1" 200 512 "https://shop.example/" "curl/8.0" "-" 0.012`,
+			want: map[string]any{
+				"client_ip": "203.0.113.9", "ident": "-", "auth": "alice", "time_local": "01/Mar/2024:12:00:00 +0000",
+				"method": "GET", "path": "/cart?id=1", "http_version": "1.1", "status": int64(200), "bytes": int64(512),
+				"referrer": "https://shop.example/", "user_agent": "curl/8.0", "forwarded_for": "-", "request_time": 0.012,
+			},
+		},
+		{
+			pattern: "%{NGINXERROR}",
+			input:   `2024/03/01 12:00:00 [error] 31#31: *7 open() "/srv/x" failed (2: No such file or directory)`,
+			want: map[string]any{
+				"level": "error", "pid": int64(31), "tid": int64(31), "connection_id": int64(7),
+				"error": `open() "/srv/x" failed (2: No such file or directory)`,
+			},
+		},
+		{
+			pattern: "%{POSTGRESQL}",
+			input:   `2024-03-01 12:00:00.123 UTC [42] app@shop LOG:  duration: 12.5 ms  statement: SELECT 1`,
+			want: map[string]any{
+				"pg_timestamp": "2024-03-01 12:00:00.123", "pid": int64(42), "user": "app", "database": "shop",
+				"level": "LOG", "duration_ms": 12.5, "statement": "SELECT 1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			g, err := compileGrok(tt.pattern, builtinGrokPatterns)
+			if err != nil {
+				t.Fatalf("compileGrok: %v", err)
+			}
+			got, ok := g.match(tt.input)
+			if !ok {
+				t.Fatalf("%s did not match %q", tt.pattern, tt.input)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBuiltinGrokPatternsCompile catches definitions RE2 rejects or that
+// reference a missing pattern.
+func TestBuiltinGrokPatternsCompile(t *testing.T) {
+	for name := range builtinGrokPatterns {
+		if _, err := compileGrok("%{"+name+"}", builtinGrokPatterns); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestGrokProcessor(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		entry  models.LogEntry
+		want   models.LogEntry
+	}{
+		{
+			name: "entry fields and context",
+			config: `
+grok_patterns: {REQ: '%{WORD:method} %{NOTSPACE:path}'}
+processors:
+  - type: grok
+    patterns: ['%{LOGLEVEL:level} %{REQ} took %{INT:took_ms:int}ms']`,
+			entry: models.LogEntry{Message: "WARN GET /a took 12ms"},
+			want: models.LogEntry{
+				Message: "WARN GET /a took 12ms",
+				Level:   "WARN",
+				Context: models.Context{"method": "GET", "path": "/a", "took_ms": int64(12)},
+			},
+		},
+		{
+			name: "first matching pattern wins",
+			config: `
+processors:
+  - type: grok
+    patterns: ['^%{INT:code:int}$', '^%{WORD:word}$']`,
+			entry: models.LogEntry{Message: "hello"},
+			want:  models.LogEntry{Message: "hello", Context: models.Context{"word": "hello"}},
+		},
+		{
+			name: "target object",
+			config: `
+processors:
+  - type: grok
+    field: context.raw
+    target: context.parsed
+    patterns: ['%{INT:http.status:int}']`,
+			entry: models.LogEntry{Message: "m", Context: models.Context{"raw": "500"}},
+			want: models.LogEntry{Message: "m", Context: models.Context{
+				"raw":    "500",
+				"parsed": map[string]any{"http": map[string]any{"status": int64(500)}},
+			}},
+		},
+		{
+			name: "prefix skips other values",
+			config: `
+processors:
+  - type: grok
+    prefix: 'GET '
+    patterns: ['%{GREEDYDATA:rest}']`,
+			entry: models.LogEntry{Message: "POST /a"},
+			want:  models.LogEntry{Message: "POST /a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPipeline(t, tt.config)
+			entry := tt.entry
+			if !p.Run(&entry) {
+				t.Fatal("entry was dropped")
+			}
+			if !reflect.DeepEqual(entry, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", entry, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrokProcessorNoMatchCountsError(t *testing.T) {
+	p := newTestPipeline(t, `
+processors:
+  - type: grok
+    patterns: ['^%{INT:n}$']`)
+	entry := models.LogEntry{Message: "abc"}
+	if !p.Run(&entry) {
+		t.Fatal("entry was dropped")
+	}
+	if stats := p.Stats(); stats[0].Errors != 1 {
+		t.Errorf("errors = %d, want 1", stats[0].Errors)
+	}
+}
+
+func TestGrokConfigErrors(t *testing.T) {
+	tests := []string{
+		`{type: grok}`,
+		`{type: grok, patterns: ['%{MISSING}']}`,
+		`{type: grok, patterns: ['%{INT}'], target: level}`,
+		`{type: grok, patterns: ['%{INT}'], unknown_option: 1}`,
+	}
+	for _, processor := range tests {
+		var c Config
+		if err := yaml.Unmarshal([]byte("processors: ["+processor+"]"), &c); err != nil {
+			t.Fatalf("yaml: %v", err)
+		}
+		if _, err := New(c); err == nil || !strings.Contains(err.Error(), "0:grok") {
+			t.Errorf("New(%s) error = %v, want a processor error", processor, err)
+		}
+	}
+}
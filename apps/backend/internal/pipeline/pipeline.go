@@ -0,0 +1,259 @@
+// Package pipeline transforms entries before they are stored. A pipeline is
+// an ordered list of processors loaded from YAML; each one may be limited to
+// matching entries with if and unless conditions.
+//
+//	processors:
+//	  - type: drop
+//	    if: {level: "<=debug", service: [checkout, cart]}
+//	  - type: parse_json
+//	    field: message
+//	  - type: rename
+//	    from: context.usr
+//	    to: context.user
+//	  - type: set
+//	    fields: {context.team: payments}
+//	  - type: grok
+//	    if: {service: nginx}
+//	    patterns: ["%{NGINXACCESS}"]
+//	  - type: redact
+//	    rules:
+//	      - {detector: email, action: hash}
+//	      - {detector: credit_card}
+//	      - {name: secrets, keys: [password, api_key], action: drop}
+package pipeline
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"github.com/aasheesh/logless/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	// GrokPatterns defines named patterns shared by every grok processor.
+	GrokPatterns map[string]string `yaml:"grok_patterns"`
+	Processors   []ProcessorConfig `yaml:"processors"`
+}
+
+// ProcessorConfig holds the settings shared by every processor. The
+// type-specific settings are decoded by the processor itself.
+type ProcessorConfig struct {
+	Type string
+	// Name identifies the processor in stats; "<index>:<type>" when empty.
+	Name string
+	// If limits the processor to entries matching every field condition.
+	If map[string]any
+	// Unless skips entries matching every field condition.
+	Unless map[string]any
+
+	options      *yaml.Node
+	grokPatterns map[string]string
+}
+
+// commonKeys are the settings in ProcessorConfig, stripped before the
+// type-specific settings are decoded.
+var commonKeys = map[string]bool{"type": true, "name": true, "if": true, "unless": true}
+
+func (c *ProcessorConfig) UnmarshalYAML(node *yaml.Node) error {
+	var common struct {
+		Type   string         `yaml:"type"`
+		Name   string         `yaml:"name"`
+		If     map[string]any `yaml:"if"`
+		Unless map[string]any `yaml:"unless"`
+	}
+	if err := node.Decode(&common); err != nil {
+		return err
+	}
+	c.Type, c.Name, c.If, c.Unless = common.Type, common.Name, common.If, common.Unless
+	c.options = node
+	return nil
+}
+
+// decodeOptions decodes the type-specific settings into out, rejecting keys
+// it does not know so typos are caught at load time.
+func (c ProcessorConfig) decodeOptions(out any) error {
+	options := &yaml.Node{Kind: yaml.MappingNode}
+	if c.options != nil {
+		for i := 0; i+1 < len(c.options.Content); i += 2 {
+			if !commonKeys[c.options.Content[i].Value] {
+				options.Content = append(options.Content, c.options.Content[i], c.options.Content[i+1])
+			}
+		}
+	}
+
+	data, err := yaml.Marshal(options)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// processor transforms one entry. It returns false to drop the entry.
+type processor interface {
+	process(entry *models.LogEntry) (bool, error)
+}
+
+// ruleCounter is implemented by processors that count matches per rule.
+type ruleCounter interface {
+	ruleCounts() map[string]uint64
+}
+
+var processorTypes = map[string]func(config ProcessorConfig) (processor, error){
+	"rename":     newRename,
+	"remove":     newRemove,
+	"set":        newSet,
+	"parse_json": newParseJSON,
+	"lowercase":  newLowercase,
+	"drop":       newDrop,
+	"grok":       newGrok,
+	"redact":     newRedact,
+}
+
+type step struct {
+	name      string
+	kind      string
+	when      condition
+	unless    condition
+	processor processor
+
+	processed atomic.Uint64
+	dropped   atomic.Uint64
+	errors    atomic.Uint64
+}
+
+type Pipeline struct {
+	steps []*step
+}
+
+// ProcessorStats counts what one processor did since the pipeline was built.
+type ProcessorStats struct {
+	Name string
+	Type string
+	// Processed counts entries the processor ran on, after its conditions.
+	Processed uint64
+	Dropped   uint64
+	// Errors counts entries the processor failed on; they still continue
+	// through the rest of the pipeline.
+	Errors uint64
+	// Rules counts matches per rule for processors that have rules, such as
+	// redact.
+	Rules map[string]uint64
+}
+
+func New(config Config) (*Pipeline, error) {
+	p := &Pipeline{}
+	for i, pc := range config.Processors {
+		name := pc.Name
+		if name == "" {
+			name = fmt.Sprintf("%d:%s", i, pc.Type)
+		}
+
+		pc.grokPatterns = config.GrokPatterns
+		build, ok := processorTypes[pc.Type]
+		if !ok {
+			return nil, fmt.Errorf("processor %s: unknown type %q", name, pc.Type)
+		}
+		proc, err := build(pc)
+		if err != nil {
+			return nil, fmt.Errorf("processor %s: %w", name, err)
+		}
+		when, err := newCondition(pc.If)
+		if err != nil {
+			return nil, fmt.Errorf("processor %s: invalid if: %w", name, err)
+		}
+		unless, err := newCondition(pc.Unless)
+		if err != nil {
+			return nil, fmt.Errorf("processor %s: invalid unless: %w", name, err)
+		}
+
+		p.steps = append(p.steps, &step{name: name, kind: pc.Type, when: when, unless: unless, processor: proc})
+	}
+	return p, nil
+}
+
+// Load reads a pipeline definition from a YAML file.
+func Load(path string) (*Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline: %w", err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse pipeline: %w", err)
+	}
+	return New(config)
+}
+
+// Run applies the processors in order and reports whether the entry should
+// be kept.
+func (p *Pipeline) Run(entry *models.LogEntry) bool {
+	for _, s := range p.steps {
+		if !s.when.matches(entry) || (len(s.unless) > 0 && s.unless.matches(entry)) {
+			continue
+		}
+
+		s.processed.Add(1)
+		keep, err := s.processor.process(entry)
+		if err != nil {
+			s.errors.Add(1)
+			continue
+		}
+		if !keep {
+			s.dropped.Add(1)
+			return false
+		}
+	}
+	return true
+}
+
+// Redact applies only the redact processors whose conditions match, e.g.
+// to an entry about to be dead-lettered instead of stored.
+func (p *Pipeline) Redact(entry *models.LogEntry) {
+	for _, s := range p.steps {
+		r, ok := s.processor.(*redact)
+		if !ok || !s.when.matches(entry) || (len(s.unless) > 0 && s.unless.matches(entry)) {
+			continue
+		}
+		r.process(entry)
+	}
+}
+
+// RedactText applies the pattern rules of every redact processor to text
+// that could not be decoded into an entry, so conditions and key rules do
+// not apply and matches are masked even for rules that drop.
+func (p *Pipeline) RedactText(text string) string {
+	for _, s := range p.steps {
+		if r, ok := s.processor.(*redact); ok {
+			text = r.redactText(text)
+		}
+	}
+	return text
+}
+
+func (p *Pipeline) Stats() []ProcessorStats {
+	stats := make([]ProcessorStats, 0, len(p.steps))
+	for _, s := range p.steps {
+		stats = append(stats, ProcessorStats{
+			Name:      s.name,
+			Type:      s.kind,
+			Processed: s.processed.Load(),
+			Dropped:   s.dropped.Load(),
+			Errors:    s.errors.Load(),
+		})
+		if counter, ok := s.processor.(ruleCounter); ok {
+			stats[len(stats)-1].Rules = counter.ruleCounts()
+		}
+	}
+	return stats
+}
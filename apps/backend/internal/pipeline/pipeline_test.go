@@ -0,0 +1,220 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aasheesh/logless/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+func newTestPipeline(t *testing.T, config string) *Pipeline {
+	t.Helper()
+	var c Config
+	if err := yaml.Unmarshal([]byte(config), &c); err != nil {
+		t.Fatalf("yaml: %v", err)
+	}
+	p, err := New(c)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return p
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{name: "valid", path: write("valid.yaml", "processors:\n  - type: drop\n    name: noise\n    if: {level: debug}\n")},
+		{name: "missing file", path: filepath.Join(dir, "missing.yaml"), wantErr: "failed to read pipeline"},
+		{name: "invalid yaml", path: write("invalid.yaml", "processors: [\n"), wantErr: "failed to parse pipeline"},
+		{name: "unknown type", path: write("type.yaml", "processors: [{type: explode}]"), wantErr: `processor 0:explode: unknown type "explode"`},
+		{name: "unknown option", path: write("option.yaml", "processors: [{type: drop, field: x}]"), wantErr: "processor 0:drop: "},
+		{name: "invalid condition", path: write("cond.yaml", "processors: [{type: drop, name: d, if: {level: '>=loud'}}]"), wantErr: "processor d: invalid if: level: "},
+		{name: "unknown condition field", path: write("field.yaml", "processors: [{type: drop, unless: {colour: red}}]"), wantErr: "processor 0:drop: invalid unless: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Load(tt.path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load: %v", err)
+				}
+				if stats := p.Stats(); len(stats) != 1 || stats[0].Name != "noise" || stats[0].Type != "drop" {
+					t.Errorf("Stats = %+v", stats)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConditions(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+		entry     models.LogEntry
+		want      bool
+	}{
+		{name: "level range", condition: `{level: "<=debug"}`, entry: models.LogEntry{Level: "trace"}, want: true},
+		{name: "level above range", condition: `{level: "<=debug"}`, entry: models.LogEntry{Level: "info"}, want: false},
+		{name: "level alias", condition: `{level: ">=warn"}`, entry: models.LogEntry{Level: "CRITICAL"}, want: true},
+		{name: "unknown level never matches", condition: `{level: ">=trace"}`, entry: models.LogEntry{Level: "loud"}, want: false},
+		{name: "any of the listed levels", condition: `{level: [debug, error]}`, entry: models.LogEntry{Level: "error"}, want: true},
+		{name: "any of the listed values", condition: `{service: [checkout, cart]}`, entry: models.LogEntry{Service: "cart"}, want: true},
+		{name: "all fields must match", condition: `{service: cart, host: h1}`, entry: models.LogEntry{Service: "cart", Host: "h2"}, want: false},
+		{name: "empty field is missing", condition: `{service: ""}`, entry: models.LogEntry{}, want: false},
+		{name: "context path", condition: `{context.http.status: 500}`, entry: models.LogEntry{Context: models.Context{"http": map[string]any{"status": json.Number("500")}}}, want: true},
+		{name: "context number against string", condition: `{context.code: "7"}`, entry: models.LogEntry{Context: models.Context{"code": 7}}, want: true},
+		{name: "context boolean", condition: `{context.ok: true}`, entry: models.LogEntry{Context: models.Context{"ok": true}}, want: true},
+		{name: "missing context", condition: `{context.user: bob}`, entry: models.LogEntry{}, want: false},
+		{name: "empty condition", condition: `{}`, entry: models.LogEntry{}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var spec map[string]any
+			if err := yaml.Unmarshal([]byte(tt.condition), &spec); err != nil {
+				t.Fatalf("yaml: %v", err)
+			}
+			c, err := newCondition(spec)
+			if err != nil {
+				t.Fatalf("newCondition: %v", err)
+			}
+			if got := c.matches(&tt.entry); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		entry  models.LogEntry
+		want   models.LogEntry
+		drop   bool
+	}{
+		{
+			name:   "rename context key to entry field",
+			config: `processors: [{type: rename, from: context.app, to: service}]`,
+			entry:  models.LogEntry{Context: models.Context{"app": "api", "k": "v"}},
+			want:   models.LogEntry{Service: "api", Context: models.Context{"k": "v"}},
+		},
+		{
+			name:   "remove",
+			config: `processors: [{type: remove, fields: [host, context.secret.token]}]`,
+			entry:  models.LogEntry{Host: "h", Context: models.Context{"secret": map[string]any{"token": "x"}, "k": "v"}},
+			want:   models.LogEntry{Context: models.Context{"k": "v"}},
+		},
+		{
+			name:   "set if missing",
+			config: `processors: [{type: set, if_missing: true, fields: {environment: prod, context.team: payments}}]`,
+			entry:  models.LogEntry{Environment: "dev"},
+			want:   models.LogEntry{Environment: "dev", Context: models.Context{"team": "payments"}},
+		},
+		{
+			name:   "parse json into a target",
+			config: `processors: [{type: parse_json, field: message, target: context.body, remove: true}]`,
+			entry:  models.LogEntry{Message: `{"n":1}`},
+			want:   models.LogEntry{Context: models.Context{"body": map[string]any{"n": json.Number("1")}}},
+		},
+		{
+			name:   "parse json into the context",
+			config: `processors: [{type: parse_json}]`,
+			entry:  models.LogEntry{Message: `{"user":"bob"}`},
+			want:   models.LogEntry{Message: `{"user":"bob"}`, Context: models.Context{"user": "bob"}},
+		},
+		{
+			name:   "lowercase",
+			config: `processors: [{type: lowercase, fields: [level, context.region]}]`,
+			entry:  models.LogEntry{Level: "WARN", Context: models.Context{"region": "EU"}},
+			want:   models.LogEntry{Level: "warn", Context: models.Context{"region": "eu"}},
+		},
+		{
+			name:   "drop with if",
+			config: `processors: [{type: drop, if: {level: "<=debug"}}, {type: set, fields: {host: never}}]`,
+			entry:  models.LogEntry{Level: "debug"},
+			drop:   true,
+		},
+		{
+			name:   "unless skips the processor",
+			config: `processors: [{type: drop, unless: {service: keep}}]`,
+			entry:  models.LogEntry{Service: "keep"},
+			want:   models.LogEntry{Service: "keep"},
+		},
+		{
+			name:   "failed processor leaves the entry to the next one",
+			config: `processors: [{type: parse_json}, {type: set, fields: {host: h}}]`,
+			entry:  models.LogEntry{Message: "not json"},
+			want:   models.LogEntry{Message: "not json", Host: "h"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPipeline(t, tt.config)
+			if keep := p.Run(&tt.entry); keep == tt.drop {
+				t.Fatalf("Run kept = %v, want %v", keep, !tt.drop)
+			}
+			if !tt.drop && !reflect.DeepEqual(tt.entry, tt.want) {
+				t.Errorf("got %+v, want %+v", tt.entry, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipelineStats(t *testing.T) {
+	p := newTestPipeline(t, `processors:
+  - {type: parse_json}
+  - {type: drop, name: debug, if: {level: debug}}`)
+	for _, entry := range []models.LogEntry{
+		{Level: "debug", Message: "{}"},
+		{Level: "info", Message: "text"},
+	} {
+		p.Run(&entry)
+	}
+
+	want := []ProcessorStats{
+		{Name: "0:parse_json", Type: "parse_json", Processed: 2, Errors: 1},
+		{Name: "debug", Type: "drop", Processed: 1, Dropped: 1},
+	}
+	if got := p.Stats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestProcessorConfigErrors(t *testing.T) {
+	for _, config := range []string{
+		`processors: [{type: rename, from: nowhere, to: service}]`,
+		`processors: [{type: remove}]`,
+		`processors: [{type: set, fields: {bogus: x}}]`,
+		`processors: [{type: parse_json, target: service}]`,
+		`processors: [{type: lowercase, fields: []}]`,
+	} {
+		var c Config
+		if err := yaml.Unmarshal([]byte(config), &c); err != nil {
+			t.Fatalf("yaml: %v", err)
+		}
+		if _, err := New(c); err == nil {
+			t.Errorf("New(%s) succeeded", config)
+		}
+	}
+}
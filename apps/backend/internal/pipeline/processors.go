@@ -0,0 +1,237 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aasheesh/logless/internal/models"
+)
+
+// rename moves a value to another field, e.g. from a context key to service.
+type rename struct {
+	from field
+	to   field
+}
+
+func newRename(config ProcessorConfig) (processor, error) {
+	var options struct {
+		From string `yaml:"from"`
+		To   string `yaml:"to"`
+	}
+	if err := config.decodeOptions(&options); err != nil {
+		return nil, err
+	}
+	from, err := parseField(options.From)
+	if err != nil {
+		return nil, fmt.Errorf("from: %w", err)
+	}
+	to, err := parseField(options.To)
+	if err != nil {
+		return nil, fmt.Errorf("to: %w", err)
+	}
+	return &rename{from: from, to: to}, nil
+}
+
+func (p *rename) process(entry *models.LogEntry) (bool, error) {
+	value, ok := p.from.get(entry)
+	if !ok {
+		return true, nil
+	}
+	p.from.remove(entry)
+	p.to.set(entry, value)
+	return true, nil
+}
+
+type remove struct {
+	fields []field
+}
+
+func newRemove(config ProcessorConfig) (processor, error) {
+	var options struct {
+		Fields []string `yaml:"fields"`
+	}
+	if err := config.decodeOptions(&options); err != nil {
+		return nil, err
+	}
+	if len(options.Fields) == 0 {
+		return nil, errors.New("fields is required")
+	}
+	fields, err := parseFields(options.Fields)
+	if err != nil {
+		return nil, err
+	}
+	return &remove{fields: fields}, nil
+}
+
+func (p *remove) process(entry *models.LogEntry) (bool, error) {
+	for _, f := range p.fields {
+		f.remove(entry)
+	}
+	return true, nil
+}
+
+// set adds static values such as team or region tags.
+type set struct {
+	fields    []field
+	values    []any
+	ifMissing bool
+}
+
+func newSet(config ProcessorConfig) (processor, error) {
+	var options struct {
+		Fields map[string]any `yaml:"fields"`
+		// IfMissing keeps values the entry already has.
+		IfMissing bool `yaml:"if_missing"`
+	}
+	if err := config.decodeOptions(&options); err != nil {
+		return nil, err
+	}
+	if len(options.Fields) == 0 {
+		return nil, errors.New("fields is required")
+	}
+
+	p := &set{ifMissing: options.IfMissing}
+	for name, value := range options.Fields {
+		f, err := parseField(name)
+		if err != nil {
+			return nil, err
+		}
+		p.fields = append(p.fields, f)
+		p.values = append(p.values, value)
+	}
+	return p, nil
+}
+
+func (p *set) process(entry *models.LogEntry) (bool, error) {
+	for i, f := range p.fields {
+		if p.ifMissing {
+			if _, ok := f.get(entry); ok {
+				continue
+			}
+		}
+		f.set(entry, p.values[i])
+	}
+	return true, nil
+}
+
+// parseJSON decodes a JSON object held in a string field and merges it into
+// the context, or under a context key when target is set.
+type parseJSON struct {
+	field  field
+	target *field
+	remove bool
+}
+
+func newParseJSON(config ProcessorConfig) (processor, error) {
+	var options struct {
+		Field  string `yaml:"field"`
+		Target string `yaml:"target"`
+		// Remove deletes the source field once it has been parsed.
+		Remove bool `yaml:"remove"`
+	}
+	if err := config.decodeOptions(&options); err != nil {
+		return nil, err
+	}
+	if options.Field == "" {
+		options.Field = "message"
+	}
+
+	f, err := parseField(options.Field)
+	if err != nil {
+		return nil, err
+	}
+	p := &parseJSON{field: f, remove: options.Remove}
+	if options.Target != "" && options.Target != "context" {
+		target, err := parseField(options.Target)
+		if err != nil || target.entry != nil {
+			return nil, fmt.Errorf("target %q must be context or context.<key>", options.Target)
+		}
+		p.target = &target
+	}
+	return p, nil
+}
+
+func (p *parseJSON) process(entry *models.LogEntry) (bool, error) {
+	value, ok := p.field.get(entry)
+	if !ok {
+		return true, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return true, fmt.Errorf("%s is not a string", p.field.name)
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	var object map[string]any
+	if err := decoder.Decode(&object); err != nil {
+		return true, fmt.Errorf("failed to parse %s as a JSON object: %w", p.field.name, err)
+	}
+
+	if p.remove {
+		p.field.remove(entry)
+	}
+	if p.target != nil {
+		p.target.set(entry, object)
+		return true, nil
+	}
+	if entry.Context == nil {
+		entry.Context = make(models.Context, len(object))
+	}
+	for k, v := range object {
+		entry.Context[k] = v
+	}
+	return true, nil
+}
+
+type lowercase struct {
+	fields []field
+}
+
+func newLowercase(config ProcessorConfig) (processor, error) {
+	var options struct {
+		Fields []string `yaml:"fields"`
+	}
+	if err := config.decodeOptions(&options); err != nil {
+		return nil, err
+	}
+	if len(options.Fields) == 0 {
+		return nil, errors.New("fields is required")
+	}
+	fields, err := parseFields(options.Fields)
+	if err != nil {
+		return nil, err
+	}
+	return &lowercase{fields: fields}, nil
+}
+
+func (p *lowercase) process(entry *models.LogEntry) (bool, error) {
+	for _, f := range p.fields {
+		value, ok := f.get(entry)
+		if !ok {
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			return true, fmt.Errorf("%s is not a string", f.name)
+		}
+		f.set(entry, strings.ToLower(s))
+	}
+	return true, nil
+}
+
+// drop discards every entry it runs on, so it is normally paired with if.
+type drop struct{}
+
+func newDrop(config ProcessorConfig) (processor, error) {
+	if err := config.decodeOptions(&struct{}{}); err != nil {
+		return nil, err
+	}
+	return drop{}, nil
+}
+
+func (drop) process(*models.LogEntry) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,416 @@
+package pipeline
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/aasheesh/logless/internal/models"
+)
+
+type detector struct {
+	pattern string
+	// validate rejects candidate matches the pattern alone cannot rule out.
+	// It is given the whole string and the bounds of the match, so it can
+	// look at what surrounds it.
+	validate func(s string, start, end int) bool
+}
+
+// detectors are the built-in rules. A capture group named "secret" limits
+// the replacement to that part, so only the token after "Bearer" is replaced.
+var detectors = map[string]detector{
+	"email":             {pattern: `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`},
+	"credit_card":       {pattern: `\b(?:[0-9][ -]?){12,18}[0-9]\b`, validate: isCardNumber},
+	"ipv4":              {pattern: `\b(?:(?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])\b`},
+	"ipv6":              {pattern: `(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}`, validate: isIPv6},
+	"bearer_token":      {pattern: `(?i)\bbearer\s+(?P<secret>[A-Za-z0-9._~+/-]+=*)`},
+	"jwt":               {pattern: `\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`},
+	"aws_access_key":    {pattern: `\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[A-Z0-9]{16}\b`},
+	"aws_secret_key":    {pattern: `(?i)aws_?secret_?access_?key\W{1,4}(?P<secret>[A-Za-z0-9/+=]{40})`},
+	"gcp_api_key":       {pattern: `\bAIza[0-9A-Za-z_-]{35}\b`},
+	"azure_storage_key": {pattern: `AccountKey=(?P<secret>[A-Za-z0-9+/=]{86,88})`},
+	"github_token":      {pattern: `\bgh[pousr]_[A-Za-z0-9]{36,255}\b`},
+	"slack_token":       {pattern: `\bxox[abprs]-[A-Za-z0-9-]{10,}`},
+	"stripe_key":        {pattern: `\b[rs]k_live_[A-Za-z0-9]{24,}\b`},
+	"private_key":       {pattern: `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`},
+}
+
+// cardBrands lists the issuer prefix ranges and number lengths of the
+// common card brands. Prefixes are compared as strings of equal length.
+var cardBrands = []struct {
+	from, to string
+	lengths  []int
+}{
+	{"4", "4", []int{13, 16, 19}},                 // Visa
+	{"51", "55", []int{16}},                       // Mastercard
+	{"2221", "2720", []int{16}},                   // Mastercard
+	{"34", "34", []int{15}},                       // American Express
+	{"37", "37", []int{15}},                       // American Express
+	{"6011", "6011", []int{16, 17, 18, 19}},       // Discover
+	{"644", "649", []int{16, 17, 18, 19}},         // Discover
+	{"65", "65", []int{16, 17, 18, 19}},           // Discover
+	{"300", "305", []int{14, 15, 16, 17, 18, 19}}, // Diners Club
+	{"36", "36", []int{14, 15, 16, 17, 18, 19}},   // Diners Club
+	{"38", "39", []int{16, 17, 18, 19}},           // Diners Club
+	{"3528", "3589", []int{16, 17, 18, 19}},       // JCB
+	{"62", "62", []int{16, 17, 18, 19}},           // UnionPay
+}
+
+// isCardNumber requires a known issuer prefix, a length valid for that
+// issuer and the Luhn checksum. The checksum alone passes one in ten digit
+// strings, such as epoch millisecond timestamps.
+func isCardNumber(s string, start, end int) bool {
+	var digits []byte
+	for i := start; i < end; i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			digits = append(digits, s[i])
+		}
+	}
+
+	known := false
+	for _, brand := range cardBrands {
+		if len(digits) < len(brand.from) {
+			continue
+		}
+		prefix := string(digits[:len(brand.from)])
+		if prefix >= brand.from && prefix <= brand.to && slices.Contains(brand.lengths, len(digits)) {
+			known = true
+			break
+		}
+	}
+	return known && luhn(digits)
+}
+
+func luhn(digits []byte) bool {
+	sum := 0
+	for i := range digits {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// isIPv6 rejects matches that run into a word or another colon, so paths
+// such as std::basic_string or tokio::fs are left alone.
+func isIPv6(s string, start, end int) bool {
+	if start > 0 && isAddressNeighbour(s[start-1]) || end < len(s) && isAddressNeighbour(s[end]) {
+		return false
+	}
+	ip := net.ParseIP(s[start:end])
+	return ip != nil && ip.To4() == nil
+}
+
+func isAddressNeighbour(c byte) bool {
+	return c == '_' || c == ':' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+const (
+	actionMask = "mask"
+	actionHash = "hash"
+	actionDrop = "drop"
+)
+
+type redactRule struct {
+	name     string
+	re       *regexp.Regexp
+	secret   int
+	validate func(s string, start, end int) bool
+	keys     map[string]bool
+	action   string
+	count    atomic.Uint64
+}
+
+// redact removes personal data and secrets from the message and the context.
+// A rule matches values by pattern or whole values by context key, and either
+// masks the match, replaces it with a keyed hash so equal values still
+// correlate, or drops the field holding it. Each redacted entry records the
+// per-rule counts under context.redacted.
+type redact struct {
+	rules        []*redactRule
+	fields       []field
+	wholeContext bool
+	hashKey      []byte
+}
+
+func newRedact(config ProcessorConfig) (processor, error) {
+	var options struct {
+		// Fields to scan; "context" scans every context value.
+		Fields []string `yaml:"fields"`
+		// HashKey keys the hash action. Without one, values with little
+		// entropy such as emails can be recovered by hashing guesses.
+		HashKey string `yaml:"hash_key"`
+		Rules   []struct {
+			Name     string   `yaml:"name"`
+			Detector string   `yaml:"detector"`
+			Pattern  string   `yaml:"pattern"`
+			Keys     []string `yaml:"keys"`
+			Action   string   `yaml:"action"`
+		} `yaml:"rules"`
+	}
+	if err := config.decodeOptions(&options); err != nil {
+		return nil, err
+	}
+	if len(options.Fields) == 0 {
+		options.Fields = []string{"message", "context"}
+	}
+	if len(options.Rules) == 0 {
+		return nil, errors.New("rules is required")
+	}
+
+	p := &redact{hashKey: []byte(options.HashKey)}
+	for _, name := range options.Fields {
+		if name == "context" {
+			p.wholeContext = true
+			continue
+		}
+		f, err := parseField(name)
+		if err != nil {
+			return nil, err
+		}
+		p.fields = append(p.fields, f)
+	}
+
+	seen := make(map[string]bool)
+	for i, rc := range options.Rules {
+		rule := &redactRule{name: rc.Name, action: rc.Action}
+		if rule.name == "" {
+			rule.name = rc.Detector
+		}
+		if rule.name == "" {
+			rule.name = fmt.Sprintf("rule%d", i)
+		}
+		if seen[rule.name] {
+			return nil, fmt.Errorf("duplicate rule name %q", rule.name)
+		}
+		seen[rule.name] = true
+
+		switch rule.action {
+		case "":
+			rule.action = actionMask
+		case actionMask, actionHash, actionDrop:
+		default:
+			return nil, fmt.Errorf("rule %s: unknown action %q, expected mask, hash or drop", rule.name, rule.action)
+		}
+
+		pattern := rc.Pattern
+		if rc.Detector != "" {
+			d, ok := detectors[rc.Detector]
+			if !ok {
+				return nil, fmt.Errorf("rule %s: unknown detector %q", rule.name, rc.Detector)
+			}
+			if pattern != "" {
+				return nil, fmt.Errorf("rule %s: set either detector or pattern", rule.name)
+			}
+			pattern, rule.validate = d.pattern, d.validate
+		}
+		if pattern != "" {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: invalid pattern: %w", rule.name, err)
+			}
+			rule.re = re
+			if i := re.SubexpIndex("secret"); i > 0 {
+				rule.secret = i
+			}
+		}
+		if len(rc.Keys) > 0 {
+			rule.keys = make(map[string]bool, len(rc.Keys))
+			for _, key := range rc.Keys {
+				rule.keys[strings.ToLower(key)] = true
+			}
+		}
+		if rule.re == nil && rule.keys == nil {
+			return nil, fmt.Errorf("rule %s: needs a detector, pattern or keys", rule.name)
+		}
+		p.rules = append(p.rules, rule)
+	}
+	return p, nil
+}
+
+func (p *redact) process(entry *models.LogEntry) (bool, error) {
+	counts := make(map[string]int)
+
+	for _, f := range p.fields {
+		value, ok := f.get(entry)
+		if !ok {
+			continue
+		}
+		key := f.name
+		if f.path != "" {
+			key = f.path[strings.LastIndex(f.path, ".")+1:]
+		}
+		if value, keep := p.redactValue(key, value, counts); !keep {
+			f.remove(entry)
+		} else {
+			f.set(entry, value)
+		}
+	}
+	if p.wholeContext && entry.Context != nil {
+		for key, value := range entry.Context {
+			if value, keep := p.redactValue(key, value, counts); !keep {
+				delete(entry.Context, key)
+			} else {
+				entry.Context[key] = value
+			}
+		}
+	}
+
+	if len(counts) == 0 {
+		return true, nil
+	}
+	redacted := make(map[string]any, len(counts))
+	for name, n := range counts {
+		redacted[name] = n
+	}
+	if entry.Context == nil {
+		entry.Context = make(models.Context)
+	}
+	entry.Context["redacted"] = redacted
+	return true, nil
+}
+
+// redactValue applies the rules to a value found under key, walking into
+// objects and arrays. It returns false when the value should be dropped.
+func (p *redact) redactValue(key string, value any, counts map[string]int) (any, bool) {
+	for _, rule := range p.rules {
+		if rule.keys[strings.ToLower(key)] {
+			counts[rule.name]++
+			rule.count.Add(1)
+			if rule.action == actionDrop {
+				return nil, false
+			}
+			return p.replacement(rule, fmt.Sprint(value)), true
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if child, keep := p.redactValue(k, child, counts); !keep {
+				delete(v, k)
+			} else {
+				v[k] = child
+			}
+		}
+		return v, true
+	case []any:
+		kept := v[:0]
+		for _, child := range v {
+			if child, keep := p.redactValue(key, child, counts); keep {
+				kept = append(kept, child)
+			}
+		}
+		return kept, true
+	case string:
+		return p.redactString(v, counts)
+	case json.Number:
+		// Card numbers are often logged as numbers.
+		if s, keep := p.redactString(v.String(), counts); !keep || s != v.String() {
+			return s, keep
+		}
+	}
+	return value, true
+}
+
+func (p *redact) redactString(s string, counts map[string]int) (any, bool) {
+	for _, rule := range p.rules {
+		if rule.re == nil {
+			continue
+		}
+		redacted, n := rule.replaceMatches(s, func(match string) string {
+			return p.replacement(rule, match)
+		})
+		if n == 0 {
+			continue
+		}
+		if rule.action == actionDrop {
+			counts[rule.name]++
+			rule.count.Add(1)
+			return nil, false
+		}
+		s = redacted
+		counts[rule.name] += n
+		rule.count.Add(uint64(n))
+	}
+	return s, true
+}
+
+// redactText masks pattern matches in text that is not part of an entry,
+// where there is no field to drop.
+func (p *redact) redactText(s string) string {
+	for _, rule := range p.rules {
+		if rule.re == nil {
+			continue
+		}
+		masked := &redactRule{name: rule.name, action: rule.action}
+		if masked.action == actionDrop {
+			masked.action = actionMask
+		}
+		redacted, n := rule.replaceMatches(s, func(match string) string {
+			return p.replacement(masked, match)
+		})
+		s = redacted
+		rule.count.Add(uint64(n))
+	}
+	return s
+}
+
+// replaceMatches replaces each validated match, or its secret group, and
+// returns the result with the number of matches.
+func (rule *redactRule) replaceMatches(s string, replace func(match string) string) (string, int) {
+	var b strings.Builder
+	last, n := 0, 0
+	for _, m := range rule.re.FindAllStringSubmatchIndex(s, -1) {
+		start, end := m[2*rule.secret], m[2*rule.secret+1]
+		if start < 0 || (rule.validate != nil && !rule.validate(s, start, end)) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(replace(s[start:end]))
+		last = end
+		n++
+	}
+	if n == 0 {
+		return s, 0
+	}
+	b.WriteString(s[last:])
+	return b.String(), n
+}
+
+func (p *redact) replacement(rule *redactRule, value string) string {
+	if rule.action != actionHash {
+		return "[REDACTED:" + rule.name + "]"
+	}
+
+	var sum []byte
+	if len(p.hashKey) > 0 {
+		mac := hmac.New(sha256.New, p.hashKey)
+		mac.Write([]byte(value))
+		sum = mac.Sum(nil)
+	} else {
+		digest := sha256.Sum256([]byte(value))
+		sum = digest[:]
+	}
+	return "[" + rule.name + ":" + hex.EncodeToString(sum[:8]) + "]"
+}
+
+func (p *redact) ruleCounts() map[string]uint64 {
+	counts := make(map[string]uint64, len(p.rules))
+	for _, rule := range p.rules {
+		counts[rule.name] = rule.count.Load()
+	}
+	return counts
+}
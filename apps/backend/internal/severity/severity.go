@@ -0,0 +1,195 @@
+// Package severity defines the canonical level scale every log entry is
+// normalised onto, from trace to fatal. The numeric values are the lower
+// bounds of the OpenTelemetry SeverityNumber ranges, so levels order
+// naturally and "level >= warn" is a plain comparison.
+package severity
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Severity int
+
+const (
+	Unknown Severity = 0
+	Trace   Severity = 1
+	Debug   Severity = 5
+	Info    Severity = 9
+	Warn    Severity = 13
+	Error   Severity = 17
+	Fatal   Severity = 21
+)
+
+var names = map[Severity]string{
+	Trace: "trace",
+	Debug: "debug",
+	Info:  "info",
+	Warn:  "warn",
+	Error: "error",
+	Fatal: "fatal",
+}
+
+func (s Severity) String() string {
+	if name, ok := names[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// FromSyslog maps an RFC 5424 severity (0 emergency .. 7 debug).
+func FromSyslog(n int) Severity {
+	switch {
+	case n <= 2:
+		return Fatal
+	case n == 3:
+		return Error
+	case n == 4:
+		return Warn
+	case n <= 6:
+		return Info
+	}
+	return Debug
+}
+
+// FromOTel maps an OpenTelemetry SeverityNumber (1-24) onto the range it
+// belongs to.
+func FromOTel(n int32) Severity {
+	switch {
+	case n >= 21:
+		return Fatal
+	case n >= 17:
+		return Error
+	case n >= 13:
+		return Warn
+	case n >= 9:
+		return Info
+	case n >= 5:
+		return Debug
+	case n >= 1:
+		return Trace
+	}
+	return Unknown
+}
+
+var (
+	aliasesMu sync.RWMutex
+	aliases   = map[string]Severity{
+		"trace": Trace, "t": Trace, "finest": Trace, "finer": Trace, "verbose": Trace,
+		"debug": Debug, "d": Debug, "dbg": Debug, "fine": Debug,
+		"info": Info, "i": Info, "information": Info, "informational": Info, "notice": Info, "config": Info,
+		"warn": Warn, "w": Warn, "warning": Warn,
+		"error": Error, "e": Error, "err": Error, "severe": Error,
+		"fatal": Fatal, "f": Fatal, "panic": Fatal, "critical": Fatal, "crit": Fatal,
+		"alert": Fatal, "emergency": Fatal, "emerg": Fatal,
+	}
+)
+
+// AddAlias maps an extra level name onto a severity, overriding the
+// built-in table.
+func AddAlias(alias string, s Severity) {
+	aliasesMu.Lock()
+	defer aliasesMu.Unlock()
+	aliases[strings.ToLower(alias)] = s
+}
+
+// Aliases returns a copy of the alias table, including aliases added with
+// AddAlias.
+func Aliases() map[string]Severity {
+	aliasesMu.RLock()
+	defer aliasesMu.RUnlock()
+	return maps.Clone(aliases)
+}
+
+// ParseAliases reads a comma-separated list such as "notice=warn,sev1=fatal"
+// and registers each alias.
+func ParseAliases(list string) error {
+	for _, pair := range strings.Split(list, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		alias, target, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid level alias %q, expected alias=level", pair)
+		}
+		s, ok := Parse(target)
+		if !ok {
+			return fmt.Errorf("invalid level alias %q: unknown level %q", pair, target)
+		}
+		AddAlias(strings.TrimSpace(alias), s)
+	}
+	return nil
+}
+
+// Parse resolves a level name through the alias table, case-insensitively.
+// Numbers 0-7 are read as syslog severities and multiples of ten from 10 to
+// 60 as the levels used by bunyan and pino.
+func Parse(level string) (Severity, bool) {
+	level = strings.ToLower(strings.TrimSpace(level))
+
+	aliasesMu.RLock()
+	s, ok := aliases[level]
+	aliasesMu.RUnlock()
+	if ok {
+		return s, true
+	}
+
+	n, err := strconv.Atoi(level)
+	if err != nil {
+		return Unknown, false
+	}
+	switch {
+	case n >= 0 && n <= 7:
+		return FromSyslog(n), true
+	case n >= 10 && n <= 60 && n%10 == 0:
+		return [...]Severity{Trace, Debug, Info, Warn, Error, Fatal}[n/10-1], true
+	}
+	return Unknown, false
+}
+
+// Range is an inclusive severity interval; zero bounds are open.
+type Range struct {
+	Min Severity
+	Max Severity
+}
+
+func (r Range) Contains(s Severity) bool {
+	return (r.Min == 0 || s >= r.Min) && (r.Max == 0 || s <= r.Max)
+}
+
+// ParseCondition reads a level query such as "warn", ">=warn", ">error" or
+// "<=info". A bare level matches exactly that level.
+func ParseCondition(condition string) (Range, error) {
+	condition = strings.TrimSpace(condition)
+
+	op := ""
+	for _, prefix := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(condition, prefix) {
+			op, condition = prefix, condition[len(prefix):]
+			break
+		}
+	}
+
+	s, ok := Parse(condition)
+	if !ok {
+		return Range{}, fmt.Errorf("unknown level %q", condition)
+	}
+
+	switch op {
+	case ">=":
+		return Range{Min: s}, nil
+	case ">":
+		return Range{Min: s + 1}, nil
+	case "<=":
+		return Range{Max: s}, nil
+	case "<":
+		if s <= Trace {
+			return Range{}, fmt.Errorf("no level below %s", s)
+		}
+		return Range{Max: s - 1}, nil
+	}
+	return Range{Min: s, Max: s}, nil
+}
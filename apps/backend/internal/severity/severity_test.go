@@ -0,0 +1,150 @@
+package severity
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		level string
+		want  Severity
+		ok    bool
+	}{
+		{"info", Info, true},
+		{" WARNING ", Warn, true},
+		{"Err", Error, true},
+		{"emerg", Fatal, true},
+		{"notice", Info, true},
+		{"finest", Trace, true},
+		{"0", Fatal, true},
+		{"3", Error, true},
+		{"4", Warn, true},
+		{"6", Info, true},
+		{"7", Debug, true},
+		{"10", Trace, true},
+		{"30", Info, true},
+		{"50", Error, true},
+		{"60", Fatal, true},
+		{"8", Unknown, false},
+		{"35", Unknown, false},
+		{"70", Unknown, false},
+		{"verbose-ish", Unknown, false},
+		{"", Unknown, false},
+	}
+	for _, tt := range tests {
+		got, ok := Parse(tt.level)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Parse(%q) = %v, %v, want %v, %v", tt.level, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFromOTel(t *testing.T) {
+	tests := []struct {
+		number int32
+		want   Severity
+	}{
+		{0, Unknown},
+		{1, Trace},
+		{4, Trace},
+		{5, Debug},
+		{9, Info},
+		{12, Info},
+		{13, Warn},
+		{17, Error},
+		{21, Fatal},
+		{24, Fatal},
+	}
+	for _, tt := range tests {
+		if got := FromOTel(tt.number); got != tt.want {
+			t.Errorf("FromOTel(%d) = %v, want %v", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		condition string
+		matches   []Severity
+		misses    []Severity
+		wantErr   bool
+	}{
+		{condition: "warn", matches: []Severity{Warn}, misses: []Severity{Info, Error}},
+		{condition: "=error", matches: []Severity{Error}, misses: []Severity{Warn, Fatal}},
+		{condition: ">=warn", matches: []Severity{Warn, Error, Fatal}, misses: []Severity{Info, Trace}},
+		{condition: ">warn", matches: []Severity{Error, Fatal}, misses: []Severity{Warn}},
+		{condition: "<=info", matches: []Severity{Trace, Debug, Info}, misses: []Severity{Warn}},
+		{condition: "<info", matches: []Severity{Trace, Debug}, misses: []Severity{Info}},
+		{condition: " >= ERR", matches: []Severity{Error, Fatal}, misses: []Severity{Warn}},
+		{condition: ">=30", matches: []Severity{Info, Warn}, misses: []Severity{Debug}},
+		{condition: "<trace", wantErr: true},
+		{condition: ">=loud", wantErr: true},
+		{condition: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		r, err := ParseCondition(tt.condition)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseCondition(%q) = %+v, expected an error", tt.condition, r)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseCondition(%q): %v", tt.condition, err)
+			continue
+		}
+		for _, s := range tt.matches {
+			if !r.Contains(s) {
+				t.Errorf("ParseCondition(%q) does not contain %v", tt.condition, s)
+			}
+		}
+		for _, s := range tt.misses {
+			if r.Contains(s) {
+				t.Errorf("ParseCondition(%q) contains %v", tt.condition, s)
+			}
+		}
+	}
+}
+
+func TestParseAliases(t *testing.T) {
+	saved := Aliases()
+	t.Cleanup(func() {
+		aliasesMu.Lock()
+		aliases = saved
+		aliasesMu.Unlock()
+	})
+
+	tests := []struct {
+		list    string
+		alias   string
+		want    Severity
+		wantErr bool
+	}{
+		{list: "sev1=fatal, Sev3 = warn", alias: "sev3", want: Warn},
+		{list: "notice=warn", alias: "NOTICE", want: Warn},
+		{list: "chatty=30", alias: "chatty", want: Info},
+		{list: "", alias: "info", want: Info},
+		{list: "broken", wantErr: true},
+		{list: "x=loud", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := ParseAliases(tt.list)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseAliases(%q): expected an error", tt.list)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseAliases(%q): %v", tt.list, err)
+			continue
+		}
+		if got, _ := Parse(tt.alias); got != tt.want {
+			t.Errorf("after ParseAliases(%q), Parse(%q) = %v, want %v", tt.list, tt.alias, got, tt.want)
+		}
+	}
+
+	copied := Aliases()
+	copied["mutated"] = Fatal
+	if _, ok := Parse("mutated"); ok {
+		t.Error("changing the map returned by Aliases changed the alias table")
+	}
+}
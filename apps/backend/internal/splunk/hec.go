@@ -0,0 +1,210 @@
+package splunk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/multiline"
+	"github.com/aasheesh/logless/internal/utils"
+)
+
+// Error is a HEC status with the numeric code Splunk clients switch on.
+type Error struct {
+	Text string `json:"text"`
+	Code int    `json:"code"`
+}
+
+func (e *Error) Error() string { return e.Text }
+
+var (
+	ErrTokenRequired = &Error{Text: "Token is required", Code: 2}
+	ErrInvalidToken  = &Error{Text: "Invalid token", Code: 4}
+	ErrNoData        = &Error{Text: "No data", Code: 5}
+	ErrInvalidFormat = &Error{Text: "Invalid data format", Code: 6}
+	ErrEventRequired = &Error{Text: "Event field is required", Code: 12}
+	ErrEventBlank    = &Error{Text: "Event field cannot be blank", Code: 13}
+)
+
+func invalidEventError(index int) *Error {
+	return &Error{Text: fmt.Sprintf("Invalid data format (event %d)", index), Code: 6}
+}
+
+// Metadata holds the defaults a raw request carries in its query string.
+type Metadata struct {
+	Host       string
+	Source     string
+	SourceType string
+	Index      string
+}
+
+type event struct {
+	Event      json.RawMessage `json:"event"`
+	Time       json.RawMessage `json:"time"`
+	Host       string          `json:"host"`
+	Source     string          `json:"source"`
+	SourceType string          `json:"sourcetype"`
+	Index      string          `json:"index"`
+	Fields     map[string]any  `json:"fields"`
+}
+
+// ParseEvents decodes the HEC event format: a stream of JSON objects that may
+// simply be concatenated without any separator.
+func ParseEvents(body []byte) ([]models.LogEntry, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var entries []models.LogEntry
+	for i := 0; ; i++ {
+		var ev event
+		err := decoder.Decode(&ev)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, invalidEventError(i)
+		}
+
+		entry, err := ev.toEntry()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrNoData
+	}
+	return entries, nil
+}
+
+func (ev *event) toEntry() (models.LogEntry, error) {
+	if len(ev.Event) == 0 || string(ev.Event) == "null" {
+		return models.LogEntry{}, ErrEventRequired
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(ev.Event))
+	decoder.UseNumber()
+	var payload any
+	if err := decoder.Decode(&payload); err != nil {
+		return models.LogEntry{}, ErrInvalidFormat
+	}
+
+	entry := models.LogEntry{Context: make(models.Context)}
+
+	switch val := payload.(type) {
+	case string:
+		if val == "" {
+			return models.LogEntry{}, ErrEventBlank
+		}
+		entry.Message = val
+	case map[string]any:
+		entry.Context = val
+		entry.Message = utils.TakeFirst(entry.Context, "message", "msg")
+		entry.Level = strings.ToLower(utils.TakeFirst(entry.Context, "level", "severity"))
+		if entry.Message == "" {
+			entry.Message = string(ev.Event)
+		}
+	default:
+		entry.Message = utils.JSONString(val)
+	}
+
+	if len(ev.Time) > 0 {
+		ts, err := parseTime(ev.Time)
+		if err != nil {
+			return models.LogEntry{}, ErrInvalidFormat
+		}
+		entry.Timestamp = ts
+	}
+
+	for k, v := range ev.Fields {
+		entry.Context[k] = v
+	}
+	Metadata{Host: ev.Host, Source: ev.Source, SourceType: ev.SourceType, Index: ev.Index}.apply(&entry)
+
+	if len(entry.Context) == 0 {
+		entry.Context = nil
+	}
+	return entry, nil
+}
+
+// ParseRaw turns a raw HEC body into one entry per non-empty line. With a
+// multiline rule, continuation lines are joined onto the entry they belong to.
+func ParseRaw(body []byte, meta Metadata, rule *multiline.Rule) ([]models.LogEntry, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), len(body)+1)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if rule == nil && strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, ErrInvalidFormat
+	}
+	if rule != nil {
+		lines = rule.Join(lines)
+	}
+
+	var entries []models.LogEntry
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		entry := models.LogEntry{Message: line}
+		meta.apply(&entry)
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrNoData
+	}
+	return entries, nil
+}
+
+func (m Metadata) apply(entry *models.LogEntry) {
+	if m.Host != "" {
+		entry.Host = m.Host
+	}
+	if m.Source != "" {
+		entry.Source = m.Source
+	}
+	for key, value := range map[string]string{
+		"sourcetype": m.SourceType,
+		"index":      m.Index,
+	} {
+		if value == "" {
+			continue
+		}
+		if entry.Context == nil {
+			entry.Context = make(models.Context)
+		}
+		entry.Context[key] = value
+	}
+}
+
+// parseTime accepts epoch seconds with an optional fractional part, either as
+// a JSON number or a string.
+func parseTime(raw json.RawMessage) (time.Time, error) {
+	s := strings.Trim(string(raw), `"`)
+	if s == "" || s == "null" {
+		return time.Time{}, nil
+	}
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, errors.New("invalid time")
+	}
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(math.Round(frac*1e6))*1e3).UTC(), nil
+}
@@ -0,0 +1,123 @@
+package splunk
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/multiline"
+)
+
+func TestParseEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []models.LogEntry
+		wantErr error
+	}{
+		{
+			name: "string event with metadata",
+			body: `{"event":"hello","time":1700000000.25,"host":"h1","source":"app","sourcetype":"json","index":"main"}`,
+			want: []models.LogEntry{{
+				Message:   "hello",
+				Timestamp: time.Unix(1700000000, 250000000).UTC(),
+				Host:      "h1",
+				Source:    "app",
+				Context:   models.Context{"sourcetype": "json", "index": "main"},
+			}},
+		},
+		{
+			name: "object event and fields",
+			body: `{"event":{"msg":"boom","severity":"ERROR","code":7},"fields":{"region":"eu"},"time":"1700000000"}`,
+			want: []models.LogEntry{{
+				Message:   "boom",
+				Level:     "error",
+				Timestamp: time.Unix(1700000000, 0).UTC(),
+				Context:   models.Context{"code": json.Number("7"), "region": "eu"},
+			}},
+		},
+		{
+			name: "concatenated events",
+			body: `{"event":"a"}{"event":"b"}` + "\n" + `{"event":42}`,
+			want: []models.LogEntry{{Message: "a"}, {Message: "b"}, {Message: "42"}},
+		},
+		{
+			name: "object without message keeps the raw event",
+			body: `{"event":{"k":"v"}}`,
+			want: []models.LogEntry{{Message: `{"k":"v"}`, Context: models.Context{"k": "v"}}},
+		},
+		{name: "empty body", body: "", wantErr: ErrNoData},
+		{name: "missing event", body: `{"host":"h"}`, wantErr: ErrEventRequired},
+		{name: "null event", body: `{"event":null}`, wantErr: ErrEventRequired},
+		{name: "blank event", body: `{"event":""}`, wantErr: ErrEventBlank},
+		{name: "bad time", body: `{"event":"a","time":"noon"}`, wantErr: ErrInvalidFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEvents([]byte(tt.body))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseEvents: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEventsInvalidJSON(t *testing.T) {
+	_, err := ParseEvents([]byte(`{"event":"a"} {"event":`))
+	var hecErr *Error
+	if !errors.As(err, &hecErr) || hecErr.Code != 6 || hecErr.Text != "Invalid data format (event 1)" {
+		t.Errorf("error = %v, want invalid data format for event 1", err)
+	}
+}
+
+func TestParseRaw(t *testing.T) {
+	rule, err := multiline.NewRule(multiline.Config{ContinuePattern: `^\s`})
+	if err != nil {
+		t.Fatalf("NewRule: %v", err)
+	}
+	meta := Metadata{Host: "h1", SourceType: "access"}
+
+	tests := []struct {
+		name string
+		body string
+		rule *multiline.Rule
+		want []string
+	}{
+		{name: "one entry per line", body: "a\r\n\n  \nb\n", want: []string{"a", "b"}},
+		{name: "multiline", body: "Exception\n  at a\n  at b\nnext\n", rule: rule, want: []string{"Exception\n  at a\n  at b", "next"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := ParseRaw([]byte(tt.body), meta, tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRaw: %v", err)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, entry.Message)
+				if entry.Host != "h1" || entry.Context["sourcetype"] != "access" {
+					t.Errorf("metadata not applied: %+v", entry)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseRaw([]byte("\n \n"), meta, nil); !errors.Is(err, ErrNoData) {
+		t.Errorf("blank body: error = %v, want ErrNoData", err)
+	}
+}
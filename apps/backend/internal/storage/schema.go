@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aasheesh/logless/internal/severity"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// schemaVersion is recorded by Migrate and checked when storage opens, so
+// a server never runs against a table missing the columns it writes. Bump
+// it whenever the migrations below change.
+const schemaVersion = 1
+
+// migrationLock is the advisory lock key that keeps two Migrate runs from
+// overlapping.
+const migrationLock = 0x6c6f676c657373
+
+// backfillBatchPages bounds the table pages one backfill statement covers,
+// so each batch commits quickly and holds few row locks.
+const backfillBatchPages = 1000
+
+// columnMigrations only change the catalog; adding a nullable column or one
+// with a constant default does not rewrite the table.
+var columnMigrations = []string{
+	`ALTER TABLE logs ADD COLUMN IF NOT EXISTS service TEXT`,
+	`ALTER TABLE logs ADD COLUMN IF NOT EXISTS environment TEXT`,
+	`ALTER TABLE logs ADD COLUMN IF NOT EXISTS host TEXT`,
+	`ALTER TABLE logs ADD COLUMN IF NOT EXISTS trace_id TEXT`,
+	`ALTER TABLE logs ADD COLUMN IF NOT EXISTS span_id TEXT`,
+	`ALTER TABLE logs ADD COLUMN IF NOT EXISTS source TEXT`,
+	`ALTER TABLE logs ADD COLUMN IF NOT EXISTS severity SMALLINT`,
+	`ALTER TABLE logs ADD COLUMN IF NOT EXISTS repeat_count INTEGER NOT NULL DEFAULT 1`,
+	`ALTER TABLE logs ADD COLUMN IF NOT EXISTS context JSONB`,
+	`CREATE TABLE IF NOT EXISTS logless_schema (version INTEGER NOT NULL)`,
+}
+
+// indexes are built with CREATE INDEX CONCURRENTLY so writes continue while
+// they are built.
+var indexes = []struct{ name, definition string }{
+	{"logs_service_environment_idx", `ON logs (service, environment, created_at DESC)`},
+	{"logs_host_idx", `ON logs (host, created_at DESC)`},
+	{"logs_trace_id_idx", `ON logs (trace_id) WHERE trace_id IS NOT NULL`},
+	{"logs_span_id_idx", `ON logs (span_id) WHERE span_id IS NOT NULL`},
+	{"logs_source_idx", `ON logs (source, created_at DESC)`},
+	{"logs_severity_idx", `ON logs (severity, created_at DESC)`},
+	{"logs_repeat_count_idx", `ON logs (repeat_count, created_at DESC) WHERE repeat_count > 1`},
+	{"logs_context_idx", `ON logs USING GIN (context jsonb_path_ops)`},
+}
+
+// Migrate brings an existing logs table up to date. It is meant to run once
+// per upgrade, from one process, before the servers start: rows written
+// before levels were normalised are backfilled in batches and the indexes are
+// built without blocking writes. Every step is idempotent, so an interrupted
+// run can simply be repeated. Level aliases must be registered beforehand.
+func Migrate(ctx context.Context, connStr string) error {
+	conn, err := pgx.Connect(ctx, connStr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	// Session-level, so it is released when the connection closes.
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLock); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+
+	for _, statement := range columnMigrations {
+		if _, err := conn.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("failed to run migration %q: %w", statement, err)
+		}
+	}
+
+	if err := backfillLevels(ctx, conn); err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		if err := createIndex(ctx, conn, index.name, index.definition); err != nil {
+			return err
+		}
+	}
+
+	_, err = conn.Exec(ctx, `WITH cleared AS (DELETE FROM logless_schema) INSERT INTO logless_schema (version) VALUES ($1)`, schemaVersion)
+	if err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+	return nil
+}
+
+// backfillLevels walks the table a range of pages at a time, so every batch
+// is bounded and no batch rescans rows an earlier one already covered. The
+// table size is read again after each batch to catch rows appended by
+// servers still running while the migration does.
+func backfillLevels(ctx context.Context, conn *pgx.Conn) error {
+	statement := levelBackfill()
+	var total int64
+	for start := int64(0); ; start += backfillBatchPages {
+		var pages int64
+		err := conn.QueryRow(ctx, `SELECT pg_relation_size('logs') / current_setting('block_size')::bigint`).Scan(&pages)
+		if err != nil {
+			return fmt.Errorf("failed to read table size: %w", err)
+		}
+		if start >= pages {
+			break
+		}
+
+		tag, err := conn.Exec(ctx, statement, start, start+backfillBatchPages)
+		if err != nil {
+			return fmt.Errorf("failed to backfill levels: %w", err)
+		}
+		if tag.RowsAffected() > 0 {
+			total += tag.RowsAffected()
+			log.Printf("Backfilled levels of %d rows, %d of %d pages done", total, min(start+backfillBatchPages, pages), pages)
+		}
+	}
+	return nil
+}
+
+// createIndex builds an index concurrently. A concurrent build that failed
+// leaves an invalid index behind, which IF NOT EXISTS would keep, so it is
+// dropped and built again.
+func createIndex(ctx context.Context, conn *pgx.Conn, name, definition string) error {
+	var invalid bool
+	err := conn.QueryRow(ctx, `SELECT NOT indisvalid FROM pg_index WHERE indexrelid = to_regclass($1)`, name).Scan(&invalid)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to check index %s: %w", name, err)
+	}
+	if invalid {
+		log.Printf("Index %s is invalid, building it again", name)
+		if _, err := conn.Exec(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+name); err != nil {
+			return fmt.Errorf("failed to drop invalid index %s: %w", name, err)
+		}
+	}
+
+	log.Printf("Creating index %s", name)
+	if _, err := conn.Exec(ctx, "CREATE INDEX CONCURRENTLY IF NOT EXISTS "+name+" "+definition); err != nil {
+		return fmt.Errorf("failed to create index %s: %w", name, err)
+	}
+	return nil
+}
+
+// undefinedTable is the SQLSTATE of a query on a table that does not exist.
+const undefinedTable = "42P01"
+
+// checkSchema fails unless Migrate has brought the database up to date.
+func checkSchema(ctx context.Context, db *pgxpool.Pool) error {
+	var version int
+	err := db.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM logless_schema`).Scan(&version)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == undefinedTable {
+		version, err = 0, nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version < schemaVersion {
+		return fmt.Errorf("database schema is at version %d, %d is required: run the consumer with -migrate", version, schemaVersion)
+	}
+	return nil
+}
+
+// levelBackfill sets the canonical level and severity of rows written before
+// levels were normalised, with the same alias table and numeric levels that
+// severity.Parse uses for new entries; unknown levels fall back to info like
+// new entries. It covers the rows in the page range [$1, $2).
+func levelBackfill() string {
+	levels := severity.Aliases()
+	for _, n := range []int{0, 1, 2, 3, 4, 5, 6, 7, 10, 20, 30, 40, 50, 60} {
+		level := strconv.Itoa(n)
+		levels[level], _ = severity.Parse(level)
+	}
+	aliases := slices.Sorted(maps.Keys(levels))
+
+	var b strings.Builder
+	b.WriteString("UPDATE logs SET level = CASE lower(trim(level))")
+	for _, alias := range aliases {
+		fmt.Fprintf(&b, " WHEN '%s' THEN '%s'", strings.ReplaceAll(alias, "'", "''"), levels[alias])
+	}
+	b.WriteString(" ELSE 'info' END, severity = CASE lower(trim(level))")
+	for _, alias := range aliases {
+		fmt.Fprintf(&b, " WHEN '%s' THEN %d", strings.ReplaceAll(alias, "'", "''"), levels[alias])
+	}
+	fmt.Fprintf(&b, " ELSE %d END", severity.Info)
+	b.WriteString(" WHERE severity IS NULL AND ctid >= format('(%s,0)', $1::bigint)::tid AND ctid < format('(%s,0)', $2::bigint)::tid")
+	return b.String()
+}
@@ -2,23 +2,45 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"time"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/aasheesh/logless/internal/models"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type LogStorage interface {
-	SaveLog(ctx context.Context, level []string, data [][]byte, jsons []string) error
+	SaveLog(ctx context.Context, rows []LogRow) error
 	GetLevelLogs(ctx context.Context, level string) ([][]byte, error)
-	GetPaginatedLogs(ctx context.Context, limit, offset int) ([][]byte, error)
+	GetPaginatedLogs(ctx context.Context, filter models.LogFilter, limit, offset int) ([][]byte, error)
 	GetSearchLogs(ctx context.Context, searchTerm string) ([][]byte, error)
 	SetLevelColors(ctx context.Context, level, color string) error
 	GetLevelColors(ctx context.Context) (map[string]string, error)
-	GetLogsCount(ctx context.Context) (int, error)
-	GetDateRangeLogs(ctx context.Context, startDate, endDate time.Time, limit, offset int) ([][]byte, error) 
-	GetDateRangeLogsCount(ctx context.Context, startDate, endDate time.Time) (int, error)
+	GetLogsCount(ctx context.Context, filter models.LogFilter) (int, error)
+	Ping(ctx context.Context) error
+}
+
+// LogRow is one stored log: the indexed columns alongside the compressed
+// entry and the text used for full-text search.
+type LogRow struct {
+	Level       string
+	Severity    int
+	Service     string
+	Environment string
+	Host        string
+	TraceID     string
+	SpanID      string
+	Source      string
+	RepeatCount int
+	// Context is the entry context as JSON, kept queryable by field.
+	Context []byte
+	Data    []byte
+	Text    string
 }
 
 type PostgresStorage struct {
@@ -35,20 +57,31 @@ func NewPostgresStorage(connStr string) (*PostgresStorage, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	if err := checkSchema(context.Background(), db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	return &PostgresStorage{db: db}, nil
 }
 
-func (s *PostgresStorage) SaveLog(ctx context.Context, levels []string, datas [][]byte, jsons []string) error {
+func (s *PostgresStorage) SaveLog(ctx context.Context, rows []LogRow) error {
 	batch := &pgx.Batch{}
-	for i := range levels {
-		batch.Queue(`INSERT INTO logs (level, compressed_data, log_text) VALUES ($1, $2, to_tsvector($3))`,
-			levels[i], datas[i], jsons[i])
+	for _, row := range rows {
+		var contextJSON any
+		if len(row.Context) > 0 {
+			contextJSON = string(row.Context)
+		}
+		batch.Queue(`INSERT INTO logs (level, severity, service, environment, host, trace_id, span_id, source, repeat_count, context, compressed_data, log_text)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10::jsonb, $11, to_tsvector($12))`,
+			row.Level, row.Severity, nullable(row.Service), nullable(row.Environment), nullable(row.Host),
+			nullable(row.TraceID), nullable(row.SpanID), nullable(row.Source), max(row.RepeatCount, 1), contextJSON, row.Data, row.Text)
 	}
 
 	br := s.db.SendBatch(ctx, batch)
 	defer br.Close()
 
-	for i := 0; i < len(levels); i++ {
+	for i := 0; i < len(rows); i++ {
 		_, err := br.Exec()
 		if err != nil {
 			return fmt.Errorf("failed batch insert at item %d: %w", i, err)
@@ -76,45 +109,12 @@ func (s *PostgresStorage) GetLevelLogs(ctx context.Context, level string) ([][]b
 	return logs, rows.Err()
 }
 
-func (s *PostgresStorage) GetDateRangeLogs(ctx context.Context, startDate, endDate time.Time, limit, offset int) ([][]byte, error) {
-	rows, err := s.db.Query(ctx,
-		`SELECT compressed_data FROM logs 
-         WHERE created_at BETWEEN $1 AND $2 
-         ORDER BY created_at DESC
-         LIMIT $3 OFFSET $4`,
-		startDate, endDate, limit, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get date range logs: %w", err)
-	}
-	defer rows.Close()
-
-	var logs [][]byte
-	for rows.Next() {
-		var data []byte
-		if err := rows.Scan(&data); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-		logs = append(logs, data)
-	}
-	return logs, rows.Err()
-}
-
-func (s *PostgresStorage) GetDateRangeLogsCount(ctx context.Context, startDate, endDate time.Time) (int, error) {
-	var count int
-	err := s.db.QueryRow(ctx,
-		`SELECT COUNT(*) FROM logs 
-         WHERE created_at BETWEEN $1 AND $2`,
-		startDate, endDate).Scan(&count)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get date range logs count: %w", err)
-	}
-	return count, nil
-}
-
-func (s *PostgresStorage) GetPaginatedLogs(ctx context.Context, limit, offset int) ([][]byte, error) {
+func (s *PostgresStorage) GetPaginatedLogs(ctx context.Context, filter models.LogFilter, limit, offset int) ([][]byte, error) {
+	where, args := filterClause(filter)
+	args = append(args, limit, offset)
 	rows, err := s.db.Query(ctx,
-		"SELECT compressed_data FROM logs ORDER BY created_at DESC LIMIT $1 OFFSET $2",
-		limit, offset)
+		fmt.Sprintf("SELECT compressed_data FROM logs%s ORDER BY created_at DESC LIMIT $%d OFFSET $%d", where, len(args)-1, len(args)),
+		args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get paginated logs: %w", err)
 	}
@@ -182,11 +182,108 @@ func (s *PostgresStorage) GetLevelColors(ctx context.Context) (map[string]string
 	return colors, rows.Err()
 }
 
-func (s *PostgresStorage) GetLogsCount(ctx context.Context) (int, error) {
+func (s *PostgresStorage) GetLogsCount(ctx context.Context, filter models.LogFilter) (int, error) {
+	where, args := filterClause(filter)
 	var count int
-	err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM logs").Scan(&count)
+	err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM logs"+where, args...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get logs count: %w", err)
 	}
 	return count, nil
 }
+
+// Ping checks that the database is reachable without touching the logs table.
+func (s *PostgresStorage) Ping(ctx context.Context) error {
+	return s.db.Ping(ctx)
+}
+
+// filterClause builds the WHERE clause, with numbered placeholders, for the
+// fields of filter that are set.
+func filterClause(filter models.LogFilter) (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	add := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	for _, field := range []struct{ column, value string }{
+		{"service", filter.Service},
+		{"environment", filter.Environment},
+		{"host", filter.Host},
+		{"trace_id", strings.ToLower(filter.TraceID)},
+		{"span_id", strings.ToLower(filter.SpanID)},
+		{"source", filter.Source},
+	} {
+		if field.value != "" {
+			add(field.column+" = $%d", field.value)
+		}
+	}
+	if filter.Level.Min != 0 {
+		add("severity >= $%d", int(filter.Level.Min))
+	}
+	if filter.Level.Max != 0 {
+		add("severity <= $%d", int(filter.Level.Max))
+	}
+	if filter.MinRepeatCount > 1 {
+		add("repeat_count >= $%d", filter.MinRepeatCount)
+	}
+	for _, path := range slices.Sorted(maps.Keys(filter.Fields)) {
+		var matches []string
+		for _, doc := range fieldDocuments(path, filter.Fields[path]) {
+			args = append(args, doc)
+			matches = append(matches, fmt.Sprintf("context @> $%d::jsonb", len(args)))
+		}
+		conditions = append(conditions, "("+strings.Join(matches, " OR ")+")")
+	}
+	if !filter.Start.IsZero() {
+		add("created_at >= $%d", filter.Start)
+	}
+	if !filter.End.IsZero() {
+		add("created_at <= $%d", filter.End)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// fieldDocuments returns the JSON documents a context must contain for path
+// to equal value. Query strings carry no type, so a value that reads as a
+// number or boolean is also tried as one, and a dotted path is tried both
+// as nested objects and as a literal key.
+func fieldDocuments(path, value string) []string {
+	values := []any{value}
+	if _, err := strconv.ParseFloat(value, 64); err == nil && json.Valid([]byte(value)) {
+		values = append(values, json.Number(value))
+	}
+	if value == "true" || value == "false" {
+		values = append(values, value == "true")
+	}
+
+	var docs []string
+	for _, v := range values {
+		nested := v
+		parts := strings.Split(path, ".")
+		for i := len(parts) - 1; i >= 0; i-- {
+			nested = map[string]any{parts[i]: nested}
+		}
+		data, _ := json.Marshal(nested)
+		docs = append(docs, string(data))
+		if len(parts) > 1 {
+			data, _ := json.Marshal(map[string]any{path: v})
+			docs = append(docs, string(data))
+		}
+	}
+	return docs
+}
+
+func nullable(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/severity"
+)
+
+func TestFilterClause(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		filter models.LogFilter
+		where  string
+		args   []any
+	}{
+		{name: "empty", filter: models.LogFilter{}},
+		{
+			name:   "indexed fields",
+			filter: models.LogFilter{Service: "api", Host: "h1", TraceID: "ABC", SpanID: "DEF"},
+			where:  " WHERE service = $1 AND host = $2 AND trace_id = $3 AND span_id = $4",
+			args:   []any{"api", "h1", "abc", "def"},
+		},
+		{
+			name:   "severity range, repeats and time",
+			filter: models.LogFilter{Level: severity.Range{Min: severity.Warn, Max: severity.Error}, MinRepeatCount: 2, Start: start},
+			where:  " WHERE severity >= $1 AND severity <= $2 AND repeat_count >= $3 AND created_at >= $4",
+			args:   []any{int(severity.Warn), int(severity.Error), 2, start},
+		},
+		{
+			name:   "a repeat count of one matches everything",
+			filter: models.LogFilter{MinRepeatCount: 1},
+		},
+		{
+			name:   "context fields in sorted order",
+			filter: models.LogFilter{Environment: "prod", Fields: map[string]string{"user": "bob", "code": "7"}},
+			where:  " WHERE environment = $1 AND (context @> $2::jsonb OR context @> $3::jsonb) AND (context @> $4::jsonb)",
+			args:   []any{"prod", `{"code":"7"}`, `{"code":7}`, `{"user":"bob"}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := filterClause(tt.filter)
+			if where != tt.where {
+				t.Errorf("where = %q, want %q", where, tt.where)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestFieldDocuments(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		value string
+		want  []string
+	}{
+		{name: "string", path: "user", value: "alice", want: []string{`{"user":"alice"}`}},
+		{name: "number", path: "code", value: "404", want: []string{`{"code":"404"}`, `{"code":404}`}},
+		{name: "float", path: "ratio", value: "0.5", want: []string{`{"ratio":"0.5"}`, `{"ratio":0.5}`}},
+		{name: "not a JSON number", path: "n", value: "0x10", want: []string{`{"n":"0x10"}`}},
+		{name: "infinity is a string", path: "n", value: "Inf", want: []string{`{"n":"Inf"}`}},
+		{name: "boolean", path: "ok", value: "true", want: []string{`{"ok":"true"}`, `{"ok":true}`}},
+		{
+			name:  "dotted path",
+			path:  "http.status",
+			value: "200",
+			want: []string{
+				`{"http":{"status":"200"}}`, `{"http.status":"200"}`,
+				`{"http":{"status":200}}`, `{"http.status":200}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fieldDocuments(tt.path, tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevelBackfill(t *testing.T) {
+	severity.AddAlias("it's bad", severity.Error)
+	statement := levelBackfill()
+	for _, want := range []string{
+		"level = CASE lower(trim(level))",
+		" WHEN 'warning' THEN 'warn'",
+		" WHEN 'warning' THEN 13",
+		" WHEN '50' THEN 'error'",
+		" WHEN 'it''s bad' THEN 17",
+		" ELSE 'info' END",
+		" ELSE 9 END",
+		// Each statement only covers its own range of pages.
+		"WHERE severity IS NULL AND ctid >= format('(%s,0)', $1::bigint)::tid AND ctid < format('(%s,0)', $2::bigint)::tid",
+	} {
+		if !strings.Contains(statement, want) {
+			t.Errorf("backfill is missing %q:\n%s", want, statement)
+		}
+	}
+}
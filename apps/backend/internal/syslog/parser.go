@@ -0,0 +1,236 @@
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+)
+
+var severityLevels = [...]string{
+	"emergency",
+	"alert",
+	"critical",
+	"error",
+	"warning",
+	"notice",
+	"info",
+	"debug",
+}
+
+var facilityNames = [...]string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// Parse decodes a single syslog message in either RFC 5424 or RFC 3164 format.
+func Parse(msg []byte) (models.LogEntry, error) {
+	msg = bytes.TrimRight(msg, "\r\n\x00")
+
+	pri, rest, err := parsePRI(msg)
+	if err != nil {
+		return models.LogEntry{}, err
+	}
+
+	entry := models.LogEntry{
+		Level:   SeverityLevel(pri % 8),
+		Context: models.Context{"facility": facilityName(pri / 8)},
+	}
+
+	// RFC 5424 messages carry a version number right after the PRI.
+	if len(rest) > 1 && rest[0] >= '1' && rest[0] <= '9' && rest[1] == ' ' {
+		err = parse5424(rest[2:], &entry)
+	} else {
+		parse3164(rest, &entry, time.Now())
+	}
+	if err != nil {
+		return models.LogEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func parsePRI(msg []byte) (int, []byte, error) {
+	if len(msg) < 3 || msg[0] != '<' {
+		return 0, nil, errors.New("missing PRI")
+	}
+	end := bytes.IndexByte(msg[:min(len(msg), 5)], '>')
+	if end < 2 {
+		return 0, nil, errors.New("malformed PRI")
+	}
+	pri, err := strconv.Atoi(string(msg[1:end]))
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, nil, fmt.Errorf("invalid PRI %q", msg[1:end])
+	}
+	return pri, msg[end+1:], nil
+}
+
+// SeverityLevel maps a syslog severity (0-7) onto a level name. Values outside
+// the range are clamped, so GELF and other syslog-derived formats can share it.
+func SeverityLevel(severity int) string {
+	return severityLevels[max(0, min(severity, len(severityLevels)-1))]
+}
+
+func facilityName(facility int) string {
+	if facility < len(facilityNames) {
+		return facilityNames[facility]
+	}
+	return strconv.Itoa(facility)
+}
+
+// parse5424 handles TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG].
+func parse5424(rest []byte, entry *models.LogEntry) error {
+	var header [5]string
+	for i := range header {
+		field, remaining, ok := bytes.Cut(rest, []byte(" "))
+		if !ok && i < len(header)-1 {
+			return errors.New("truncated RFC 5424 header")
+		}
+		header[i] = string(field)
+		rest = remaining
+	}
+
+	if header[0] != "-" {
+		ts, err := time.Parse(time.RFC3339Nano, header[0])
+		if err != nil {
+			return fmt.Errorf("invalid RFC 5424 timestamp: %w", err)
+		}
+		entry.Timestamp = ts
+	}
+	entry.Host = headerValue(header[1])
+	entry.Service = headerValue(header[2])
+	setContext(entry, "procid", header[3])
+	setContext(entry, "msgid", header[4])
+
+	rest, err := parseStructuredData(rest, entry)
+	if err != nil {
+		return err
+	}
+
+	rest = bytes.TrimPrefix(rest, []byte(" "))
+	rest = bytes.TrimPrefix(rest, []byte("\xef\xbb\xbf"))
+	entry.Message = string(rest)
+	return nil
+}
+
+// parseStructuredData consumes either the nil value or a run of SD-ELEMENTs,
+// storing each SD-PARAM in the context as "sd.<SD-ID>.<PARAM-NAME>".
+func parseStructuredData(rest []byte, entry *models.LogEntry) ([]byte, error) {
+	if len(rest) == 0 {
+		return rest, nil
+	}
+	if rest[0] == '-' {
+		return rest[1:], nil
+	}
+
+	for len(rest) > 0 && rest[0] == '[' {
+		rest = rest[1:]
+
+		idEnd := bytes.IndexAny(rest, " ]")
+		if idEnd < 1 {
+			return nil, errors.New("malformed structured data")
+		}
+		id := string(rest[:idEnd])
+		rest = rest[idEnd:]
+
+		for len(rest) > 0 && rest[0] == ' ' {
+			rest = rest[1:]
+
+			eq := bytes.IndexByte(rest, '=')
+			if eq < 1 || len(rest) < eq+2 || rest[eq+1] != '"' {
+				return nil, errors.New("malformed structured data parameter")
+			}
+			name := string(rest[:eq])
+			rest = rest[eq+2:]
+
+			var value strings.Builder
+			closed := false
+			for i := 0; i < len(rest); i++ {
+				c := rest[i]
+				if c == '\\' && i+1 < len(rest) && (rest[i+1] == '"' || rest[i+1] == '\\' || rest[i+1] == ']') {
+					value.WriteByte(rest[i+1])
+					i++
+					continue
+				}
+				if c == '"' {
+					rest = rest[i+1:]
+					closed = true
+					break
+				}
+				value.WriteByte(c)
+			}
+			if !closed {
+				return nil, errors.New("unterminated structured data value")
+			}
+			entry.Context["sd."+id+"."+name] = value.String()
+		}
+
+		if len(rest) == 0 || rest[0] != ']' {
+			return nil, errors.New("unterminated structured data element")
+		}
+		rest = rest[1:]
+	}
+	return rest, nil
+}
+
+// parse3164 is deliberately lenient: BSD syslog has no real grammar, so
+// anything that does not match "Mmm dd hh:mm:ss HOSTNAME TAG: MSG" is kept as
+// the message body.
+func parse3164(rest []byte, entry *models.LogEntry, now time.Time) {
+	const stampLen = len(time.Stamp)
+
+	if len(rest) >= stampLen+1 && rest[stampLen] == ' ' {
+		if ts, err := time.ParseInLocation(time.Stamp, string(rest[:stampLen]), now.Location()); err == nil {
+			ts = ts.AddDate(now.Year(), 0, 0)
+			// Messages stamped just before New Year arrive just after it.
+			if ts.After(now.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+			entry.Timestamp = ts
+			rest = rest[stampLen+1:]
+
+			if host, remaining, ok := bytes.Cut(rest, []byte(" ")); ok && len(host) > 0 && !bytes.HasSuffix(host, []byte(":")) {
+				entry.Host = string(host)
+				rest = remaining
+			}
+		}
+	}
+
+	if tagEnd := bytes.IndexAny(rest, ":[ "); tagEnd > 0 && tagEnd <= 48 {
+		tag := string(rest[:tagEnd])
+		remaining := rest[tagEnd:]
+
+		if remaining[0] == '[' {
+			if pidEnd := bytes.IndexByte(remaining, ']'); pidEnd > 0 {
+				setContext(entry, "procid", string(remaining[1:pidEnd]))
+				remaining = remaining[pidEnd+1:]
+			}
+		}
+		if bytes.HasPrefix(remaining, []byte(":")) {
+			entry.Service = tag
+			rest = bytes.TrimPrefix(remaining[1:], []byte(" "))
+		}
+	}
+
+	entry.Message = string(rest)
+}
+
+func setContext(entry *models.LogEntry, key, value string) {
+	if value = headerValue(value); value == "" {
+		return
+	}
+	entry.Context[key] = value
+}
+
+// headerValue maps the NILVALUE "-" to an empty string.
+func headerValue(value string) string {
+	if value == "-" {
+		return ""
+	}
+	return value
+}
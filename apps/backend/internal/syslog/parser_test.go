@@ -0,0 +1,164 @@
+package syslog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+)
+
+func TestParse5424(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want models.LogEntry
+	}{
+		{
+			name: "full header with structured data",
+			msg:  `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 42 ID47 [exampleSDID@32473 iut="3" eventSource="Application"] An application event`,
+			want: models.LogEntry{
+				Level:     "notice",
+				Message:   "An application event",
+				Timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+				Host:      "mymachine.example.com",
+				Service:   "evntslog",
+				Context: models.Context{
+					"facility":                         "local4",
+					"procid":                           "42",
+					"msgid":                            "ID47",
+					"sd.exampleSDID@32473.iut":         "3",
+					"sd.exampleSDID@32473.eventSource": "Application",
+				},
+			},
+		},
+		{
+			name: "nil values and BOM",
+			msg:  "<34>1 - - - - - - \xef\xbb\xbf'su root' failed\n",
+			want: models.LogEntry{
+				Level:   "critical",
+				Message: "'su root' failed",
+				Context: models.Context{"facility": "auth"},
+			},
+		},
+		{
+			name: "escaped structured data value",
+			msg:  `<14>1 - host app - - [a@1 k="say \"hi\" \] \\"]`,
+			want: models.LogEntry{
+				Level:   "info",
+				Host:    "host",
+				Service: "app",
+				Context: models.Context{"facility": "user", "sd.a@1.k": `say "hi" ] \`},
+			},
+		},
+		{
+			name: "several elements without message",
+			msg:  `<13>1 - - - - - [a@1 x="1"][b@1 y="2"]`,
+			want: models.LogEntry{
+				Level:   "notice",
+				Context: models.Context{"facility": "user", "sd.a@1.x": "1", "sd.b@1.y": "2"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.msg))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse3164(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 10, 0, time.UTC)
+	tests := []struct {
+		name string
+		msg  string
+		want models.LogEntry
+	}{
+		{
+			name: "timestamp host and tag with pid",
+			msg:  "Oct 11 22:14:15 mymachine su[123]: 'su root' failed",
+			want: models.LogEntry{
+				Timestamp: time.Date(2024, 10, 11, 22, 14, 15, 0, time.UTC).AddDate(-1, 0, 0),
+				Host:      "mymachine",
+				Service:   "su",
+				Message:   "'su root' failed",
+				Context:   models.Context{"procid": "123"},
+			},
+		},
+		{
+			name: "previous year across new year",
+			msg:  "Dec 31 23:59:59 host cron: job done",
+			want: models.LogEntry{
+				Timestamp: time.Date(2023, 12, 31, 23, 59, 59, 0, time.UTC),
+				Host:      "host",
+				Service:   "cron",
+				Message:   "job done",
+				Context:   models.Context{},
+			},
+		},
+		{
+			name: "tag without timestamp",
+			msg:  "kernel: link up",
+			want: models.LogEntry{Service: "kernel", Message: "link up", Context: models.Context{}},
+		},
+		{
+			name: "free text is kept as is",
+			msg:  "just some text",
+			want: models.LogEntry{Message: "just some text", Context: models.Context{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := models.LogEntry{Context: models.Context{}}
+			parse3164([]byte(tt.msg), &entry, now)
+			if !reflect.DeepEqual(entry, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", entry, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+	}{
+		{name: "missing PRI", msg: "hello"},
+		{name: "unterminated PRI", msg: "<13 hello"},
+		{name: "PRI out of range", msg: "<192>hello"},
+		{name: "truncated header", msg: "<13>1 2003-10-11T22:14:15Z host"},
+		{name: "bad timestamp", msg: "<13>1 yesterday host app - - -"},
+		{name: "unterminated structured data", msg: `<13>1 - - - - - [a@1 x="1"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if entry, err := Parse([]byte(tt.msg)); err == nil {
+				t.Errorf("expected an error, got %+v", entry)
+			}
+		})
+	}
+}
+
+func TestSeverityLevel(t *testing.T) {
+	tests := []struct {
+		severity int
+		want     string
+	}{
+		{-1, "emergency"},
+		{0, "emergency"},
+		{3, "error"},
+		{7, "debug"},
+		{9, "debug"},
+	}
+	for _, tt := range tests {
+		if got := SeverityLevel(tt.severity); got != tt.want {
+			t.Errorf("SeverityLevel(%d) = %q, want %q", tt.severity, got, tt.want)
+		}
+	}
+}
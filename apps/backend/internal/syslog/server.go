@@ -0,0 +1,232 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+
+	producer "github.com/aasheesh/logless/internal/kafka"
+)
+
+const (
+	maxUDPMessageSize = 64 * 1024
+	maxTCPMessageSize = 1 << 20
+)
+
+// Server receives syslog messages over UDP, TCP and TLS and publishes them
+// through the Kafka producer.
+type Server struct {
+	producer *producer.LogProducer
+
+	mu      sync.Mutex
+	closers []io.Closer
+	conns   map[net.Conn]struct{}
+	wg      sync.WaitGroup
+	closed  bool
+}
+
+func NewServer(producer *producer.LogProducer) *Server {
+	return &Server{producer: producer, conns: make(map[net.Conn]struct{})}
+}
+
+func (s *Server) ListenUDP(addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on udp %s: %w", addr, err)
+	}
+	if !s.track(conn) {
+		return net.ErrClosed
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		buf := make([]byte, maxUDPMessageSize)
+		for {
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Printf("Syslog UDP read error: %v", err)
+				}
+				return
+			}
+			s.handle(buf[:n], peer)
+		}
+	}()
+
+	log.Printf("Syslog listener ready on udp %s", addr)
+	return nil
+}
+
+func (s *Server) ListenTCP(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on tcp %s: %w", addr, err)
+	}
+	return s.serve(ln, "tcp", addr)
+}
+
+func (s *Server) ListenTLS(addr, certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+
+	ln, err := tls.Listen("tcp", addr, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to listen on tls %s: %w", addr, err)
+	}
+	return s.serve(ln, "tls", addr)
+}
+
+// Close stops every listener and open connection and waits for the reader
+// goroutines to exit.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for _, c := range s.closers {
+		c.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Server) track(c io.Closer) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		c.Close()
+		return false
+	}
+	s.closers = append(s.closers, c)
+	return true
+}
+
+func (s *Server) serve(ln net.Listener, network, addr string) error {
+	if !s.track(ln) {
+		return net.ErrClosed
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Printf("Syslog %s accept error: %v", network, err)
+				}
+				return
+			}
+
+			s.mu.Lock()
+			if s.closed {
+				s.mu.Unlock()
+				conn.Close()
+				return
+			}
+			s.conns[conn] = struct{}{}
+			s.wg.Add(1)
+			s.mu.Unlock()
+
+			go func() {
+				defer s.wg.Done()
+				s.handleConn(conn)
+
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+			}()
+		}
+	}()
+
+	log.Printf("Syslog listener ready on %s %s", network, addr)
+	return nil
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		frame, err := readFrame(reader)
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				log.Printf("Syslog connection from %s closed: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		if len(frame) > 0 {
+			s.handle(frame, conn.RemoteAddr())
+		}
+	}
+}
+
+// readFrame supports both TCP framings from RFC 6587: octet counting
+// ("<len> <msg>") and non-transparent framing terminated by LF.
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if first[0] >= '1' && first[0] <= '9' {
+		prefix, err := r.ReadSlice(' ')
+		if err != nil {
+			return nil, fmt.Errorf("malformed octet count: %w", err)
+		}
+		size, err := strconv.Atoi(string(prefix[:len(prefix)-1]))
+		if err != nil || size > maxTCPMessageSize {
+			return nil, fmt.Errorf("invalid octet count %q", prefix)
+		}
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return nil, err
+		}
+		return frame, nil
+	}
+
+	var frame []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		frame = append(frame, chunk...)
+		if len(frame) > maxTCPMessageSize {
+			return nil, errors.New("message exceeds maximum size")
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil && (err != io.EOF || len(frame) == 0) {
+			return nil, err
+		}
+		return bytes.TrimRight(frame, "\r\n"), nil
+	}
+}
+
+// handle parses and forwards one message. Unparseable messages are logged by
+// size and sender only, as their content may hold secrets.
+func (s *Server) handle(msg []byte, peer net.Addr) {
+	entry, err := Parse(msg)
+	if err != nil {
+		log.Printf("Failed to parse syslog message of %d bytes from %s: %v", len(msg), peer, err)
+		return
+	}
+	if err := s.producer.SendLog(entry); err != nil {
+		log.Printf("Failed to send syslog message to Kafka: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package syslog
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "octet counting",
+			input: "11 <13>1 - - -5 hello",
+			want:  []string{"<13>1 - - -", "hello"},
+		},
+		{
+			name:  "octet counting keeps newlines",
+			input: "7 a\nb\r\ncd",
+			want:  []string{"a\nb\r\ncd"},
+		},
+		{
+			name:  "non-transparent framing",
+			input: "<13>first\r\n<13>second\n<13>last",
+			want:  []string{"<13>first", "<13>second", "<13>last"},
+		},
+		{
+			name:  "mixed framings",
+			input: "<13>plain\n5 count",
+			want:  []string{"<13>plain", "count"},
+		},
+		{
+			name:    "truncated octet count frame",
+			input:   "10 short",
+			wantErr: true,
+		},
+		{
+			name:    "octet count over the limit",
+			input:   "99999999 x",
+			wantErr: true,
+		},
+		{
+			name:    "octet count without space",
+			input:   "12",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.input))
+			var got []string
+			for {
+				frame, err := readFrame(r)
+				if errors.Is(err, io.EOF) && !tt.wantErr {
+					break
+				}
+				if err != nil {
+					if !tt.wantErr {
+						t.Fatalf("readFrame: %v", err)
+					}
+					return
+				}
+				got = append(got, string(frame))
+			}
+			if tt.wantErr {
+				t.Fatalf("expected an error, got frames %q", got)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadFrameTooLarge(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("<13>" + strings.Repeat("x", maxTCPMessageSize+1) + "\n"))
+	if _, err := readFrame(r); err == nil {
+		t.Fatal("expected an error for an oversized LF-terminated frame")
+	}
+}
@@ -0,0 +1,217 @@
+// Package textlog turns unstructured and logfmt lines into log entries and
+// infers a level from the tokens commonly found in plain-text logs.
+package textlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/aasheesh/logless/internal/models"
+	"github.com/aasheesh/logless/internal/severity"
+	"github.com/aasheesh/logless/internal/utils"
+)
+
+var (
+	messageKeys = []string{"msg", "message"}
+	levelKeys   = []string{"level", "lvl", "severity", "loglevel"}
+	timeKeys    = []string{"time", "ts", "timestamp"}
+)
+
+// ParseLines returns one entry per non-empty line of body.
+func ParseLines(body []byte) ([]models.LogEntry, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), len(body)+1)
+
+	var entries []models.LogEntry
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		entries = append(entries, ParseLine(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ParseLine extracts the key=value pairs of a line into the entry context.
+// When the line carries a msg or message key that becomes the message,
+// otherwise the whole line is kept. Level and time keys are lifted onto the
+// entry; without a level key the level is detected from the text.
+func ParseLine(line string) models.LogEntry {
+	entry := models.LogEntry{Message: line}
+
+	pairs := ParseLogfmt(line)
+	if message, ok := take(pairs, messageKeys); ok && message != "" {
+		entry.Message = message
+	}
+	if level, ok := take(pairs, levelKeys); ok {
+		entry.Level = normalizeLevel(level)
+	}
+	if raw, ok := take(pairs, timeKeys); ok {
+		if ts, err := models.ParseTimestampString(raw); err == nil && !ts.IsZero() {
+			entry.Timestamp = ts
+		} else {
+			pairs["time"] = raw
+		}
+	}
+	if entry.Level == "" {
+		entry.Level = DetectLevel(line)
+	}
+
+	if len(pairs) > 0 {
+		entry.Context = pairs
+	}
+	return entry
+}
+
+// take removes the first of keys present in pairs and returns its value.
+func take(pairs map[string]any, keys []string) (string, bool) {
+	for _, key := range keys {
+		if value, ok := pairs[key]; ok {
+			delete(pairs, key)
+			return utils.JSONString(value), true
+		}
+	}
+	return "", false
+}
+
+// ParseLogfmt returns the key=value pairs found in line. Values may be double
+// quoted with Go-style escapes. Unquoted numbers and booleans are typed, so
+// fields like duration_ms=42 can be compared numerically. Words without '='
+// are skipped, so pairs can be pulled out of otherwise free-form text.
+func ParseLogfmt(line string) map[string]any {
+	pairs := make(map[string]any)
+
+	for i := 0; i < len(line); {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		start := i
+		for i < len(line) && line[i] != ' ' && line[i] != '=' && line[i] != '"' {
+			i++
+		}
+		key := line[start:i]
+
+		if i >= len(line) || line[i] != '=' || key == "" {
+			// Not a pair; skip the rest of the word.
+			for i < len(line) && line[i] != ' ' {
+				if line[i] == '"' {
+					i = skipQuoted(line, i)
+				} else {
+					i++
+				}
+			}
+			continue
+		}
+		i++
+
+		if i < len(line) && line[i] == '"' {
+			end := skipQuoted(line, i)
+			pairs[key] = unquote(line[i:end])
+			i = end
+		} else {
+			start := i
+			for i < len(line) && line[i] != ' ' {
+				i++
+			}
+			pairs[key] = typedValue(line[start:i])
+		}
+	}
+	return pairs
+}
+
+// typedValue turns bare true/false and JSON numbers into typed values and
+// leaves everything else, including numbers with leading zeros, as a string.
+func typedValue(value string) any {
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if value != "" && json.Valid([]byte(value)) && (value[0] == '-' || (value[0] >= '0' && value[0] <= '9')) {
+		return json.Number(value)
+	}
+	return value
+}
+
+// skipQuoted returns the index just past the quoted string starting at i.
+func skipQuoted(line string, i int) int {
+	for i++; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(line)
+}
+
+func unquote(quoted string) string {
+	s := strings.TrimPrefix(quoted, `"`)
+	s = strings.TrimSuffix(s, `"`)
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+var (
+	levelPair = regexp.MustCompile(`(?i)\b(?:level|lvl|severity)\s*[=:]\s*"?([a-z]+)`)
+	// [WARN], <error>, (debug) and similar bracketed markers, in any case.
+	bracketLevel = regexp.MustCompile(`(?i)[\[<(](fatal|panic|crit|critical|error|err|warning|warn|info|debug|trace)[\]>)]`)
+	// Single letters such as [E] are common in prose, "(e)", so they only
+	// count in square or angle brackets opening a line.
+	bracketLetter = regexp.MustCompile(`(?im)^\s*[\[<]([fewidt])[\]>]`)
+	// glog/klog headers such as "E0102 15:04:05.000000".
+	glogLevel = regexp.MustCompile(`^([FEWI])\d{4} \d{2}:\d{2}:\d{2}`)
+	// Bare words only count when upper case, "no error" is not an error line.
+	upperLevel = regexp.MustCompile(`\b(FATAL|PANIC|CRIT|CRITICAL|ERROR|ERR|WARNING|WARN|INFO|DEBUG|TRACE)\b`)
+)
+
+// DetectLevel infers a level from common markers in free text, trying
+// explicit level= pairs first, then bracketed tags and letters, glog headers
+// and upper case words. It returns an empty string when nothing matches.
+func DetectLevel(text string) string {
+	for _, re := range []*regexp.Regexp{levelPair, bracketLevel, bracketLetter, glogLevel, upperLevel} {
+		if m := re.FindStringSubmatch(text); m != nil {
+			if level := normalizeLevel(m[1]); level != "" {
+				return level
+			}
+		}
+	}
+	return ""
+}
+
+func normalizeLevel(token string) string {
+	if s, ok := severity.Parse(token); ok {
+		return s.String()
+	}
+	return strings.ToLower(token)
+}
@@ -0,0 +1,120 @@
+package textlog
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aasheesh/logless/internal/models"
+)
+
+func TestParseLogfmt(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]any
+	}{
+		{
+			line: `level=info msg="request done" status=200 duration_ms=4.5 cached=true`,
+			want: map[string]any{"level": "info", "msg": "request done", "status": json.Number("200"), "duration_ms": json.Number("4.5"), "cached": true},
+		},
+		{
+			line: `path=/a?b=c id=007 neg=-3 empty= flag=false`,
+			want: map[string]any{"path": "/a?b=c", "id": "007", "neg": json.Number("-3"), "empty": "", "flag": false},
+		},
+		{
+			line: `msg="say \"hi\"\n\tbye" user=bob`,
+			want: map[string]any{"msg": "say \"hi\"\n\tbye", "user": "bob"},
+		},
+		{
+			line: `Started GET "/users" user_id=42 for 127.0.0.1`,
+			want: map[string]any{"user_id": json.Number("42")},
+		},
+		{
+			line: `  spaced=1   out=2  `,
+			want: map[string]any{"spaced": json.Number("1"), "out": json.Number("2")},
+		},
+		{line: `=orphan "quoted word" plain`, want: map[string]any{}},
+		{line: `msg="unterminated`, want: map[string]any{"msg": "unterminated"}},
+	}
+	for _, tt := range tests {
+		if got := ParseLogfmt(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseLogfmt(%q)\n got  %#v\n want %#v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want models.LogEntry
+	}{
+		{
+			line: `time=2024-03-01T12:00:00Z level=WARN msg="disk almost full" pct=91`,
+			want: models.LogEntry{
+				Message:   "disk almost full",
+				Level:     "warn",
+				Timestamp: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+				Context:   models.Context{"pct": json.Number("91")},
+			},
+		},
+		{
+			line: `ts=1709294400 lvl=err message=boom`,
+			want: models.LogEntry{Message: "boom", Level: "error", Timestamp: time.Unix(1709294400, 0).UTC()},
+		},
+		{
+			line: `ts=yesterday msg=x`,
+			want: models.LogEntry{Message: "x", Context: models.Context{"time": "yesterday"}},
+		},
+		{
+			line: `2024-03-01 12:00:00 ERROR connection refused`,
+			want: models.LogEntry{Message: "2024-03-01 12:00:00 ERROR connection refused", Level: "error"},
+		},
+		{
+			line: `msg= user=bob`,
+			want: models.LogEntry{Message: "msg= user=bob", Context: models.Context{"user": "bob"}},
+		},
+	}
+	for _, tt := range tests {
+		if got := ParseLine(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseLine(%q)\n got  %+v\n want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseLines(t *testing.T) {
+	entries, err := ParseLines([]byte("first line\n\n  \nlevel=debug msg=second\r\n"))
+	if err != nil {
+		t.Fatalf("ParseLines: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Message != "first line" || entries[1].Message != "second" || entries[1].Level != "debug" {
+		t.Errorf("got %+v", entries)
+	}
+}
+
+func TestDetectLevel(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{`something severity: Warning happened`, "warn"},
+		{`[E] cannot connect`, "error"},
+		{`<crit> kernel panic`, "fatal"},
+		{`(debug) cache miss`, "debug"},
+		{`E0102 15:04:05.000000 1 main.go:10] failed`, "error"},
+		{`I0102 15:04:05.000000 1 main.go:10] ok`, "info"},
+		{`2024-03-01 FATAL out of memory`, "fatal"},
+		{`no error was found`, ""},
+		{`plain text`, ""},
+		{`[x] done`, ""},
+		{`  <W> disk almost full`, "warn"},
+		{"starting\n[D] cache miss", "debug"},
+		{`see note (e) below`, ""},
+		{`pick option [i] from the menu`, ""},
+	}
+	for _, tt := range tests {
+		if got := DetectLevel(tt.text); got != tt.want {
+			t.Errorf("DetectLevel(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
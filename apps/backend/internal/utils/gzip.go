@@ -1 +1,100 @@
-package utils
\ No newline at end of file
+package utils
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	ErrUnsupportedEncoding  = errors.New("unsupported content encoding")
+	ErrDecompressedTooLarge = errors.New("decompressed body exceeds size limit")
+)
+
+// NewDecompressingReader wraps r according to a Content-Encoding header value.
+// Reads fail with ErrDecompressedTooLarge once more than maxSize bytes have been
+// produced, which guards against decompression bombs.
+func NewDecompressingReader(r io.Reader, encoding string, maxSize int64) (io.ReadCloser, error) {
+	var (
+		decoded io.ReadCloser
+		err     error
+	)
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		decoded = io.NopCloser(r)
+	case "gzip", "x-gzip":
+		decoded, err = gzip.NewReader(r)
+	case "deflate":
+		decoded, err = newDeflateReader(r)
+	case "zstd":
+		var zr *zstd.Decoder
+		zr, err = zstd.NewReader(r, zstd.WithDecoderMaxMemory(uint64(maxSize)))
+		if err == nil {
+			decoded = zr.IOReadCloser()
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, encoding)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s reader: %w", encoding, err)
+	}
+
+	return &limitedReadCloser{rc: decoded, remaining: maxSize}, nil
+}
+
+// newDeflateReader accepts both zlib-wrapped streams, which is what RFC 9110
+// specifies, and the raw DEFLATE streams many clients send instead.
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// maxEmptyReads bounds how many (0, nil) reads the size probe tolerates, as
+// bufio.Reader does.
+const maxEmptyReads = 100
+
+type limitedReadCloser struct {
+	rc        io.ReadCloser
+	remaining int64
+}
+
+func (l *limitedReadCloser) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// Probe for one more byte so a body of exactly maxSize is not rejected.
+		// A decoder may return (0, nil) before it reaches the end of the
+		// stream, so only io.EOF proves the body fits.
+		var probe [1]byte
+		for range maxEmptyReads {
+			n, err := l.rc.Read(probe[:])
+			if n > 0 {
+				return 0, ErrDecompressedTooLarge
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+		return 0, io.ErrNoProgress
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.rc.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+func (l *limitedReadCloser) Close() error {
+	return l.rc.Close()
+}
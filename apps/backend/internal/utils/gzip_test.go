@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// stutterReader returns (0, nil) before every chunk it hands out.
+type stutterReader struct {
+	r     io.Reader
+	empty bool
+}
+
+func (s *stutterReader) Read(p []byte) (int, error) {
+	if s.empty = !s.empty; s.empty {
+		return 0, nil
+	}
+	return s.r.Read(p)
+}
+
+func (s *stutterReader) Close() error { return nil }
+
+func gzipped(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestNewDecompressingReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+		maxSize  int64
+		want     string
+		wantErr  error
+	}{
+		{name: "identity", body: []byte("hello"), encoding: "", maxSize: 10, want: "hello"},
+		{name: "gzip", body: gzipped(t, "hello"), encoding: "gzip", maxSize: 10, want: "hello"},
+		{name: "exactly the limit", body: gzipped(t, "hello"), encoding: "x-gzip", maxSize: 5, want: "hello"},
+		{name: "over the limit", body: gzipped(t, "hello!"), encoding: "gzip", maxSize: 5, wantErr: ErrDecompressedTooLarge},
+		{name: "unsupported", body: nil, encoding: "br", maxSize: 5, wantErr: ErrUnsupportedEncoding},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewDecompressingReader(bytes.NewReader(tt.body), tt.encoding, tt.maxSize)
+			if err == nil {
+				var got []byte
+				got, err = io.ReadAll(r)
+				if err == nil && string(got) != tt.want {
+					t.Errorf("got %q, want %q", got, tt.want)
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLimitedReadCloserEmptyReads(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{name: "fits", data: "12345", wantErr: nil},
+		{name: "too large", data: "123456", wantErr: ErrDecompressedTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &limitedReadCloser{rc: &stutterReader{r: strings.NewReader(tt.data)}, remaining: 5}
+			if _, err := io.ReadAll(l); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
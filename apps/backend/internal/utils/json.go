@@ -1 +1,137 @@
-package utils
\ No newline at end of file
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONString renders a decoded JSON value as a plain string: strings are
+// returned unchanged and everything else is re-encoded.
+func JSONString(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case bool:
+		return strconv.FormatBool(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case json.Number:
+		return val.String()
+	case nil:
+		return ""
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(data)
+	}
+}
+
+// TakeFirst removes and returns, as a string, the first non-empty scalar
+// value among keys. A dotted key such as "log.level" matches either a literal
+// key or the same path through nested objects; parents left empty are removed
+// too. Objects and arrays are skipped and left in place, so an ECS object such
+// as {"host": {"name": ...}} is never flattened into a JSON string.
+func TakeFirst(context map[string]any, keys ...string) string {
+	for _, key := range keys {
+		value, ok := LookupPath(context, key)
+		if !ok {
+			continue
+		}
+		switch value.(type) {
+		case map[string]any, []any:
+			continue
+		}
+		if s := JSONString(value); s != "" {
+			DeletePath(context, key)
+			return s
+		}
+	}
+	return ""
+}
+
+// LookupPath finds a dotted key the way TakeFirst does, without removing it.
+func LookupPath(m map[string]any, key string) (any, bool) {
+	if value, ok := m[key]; ok {
+		return value, true
+	}
+	head, rest, found := strings.Cut(key, ".")
+	if !found {
+		return nil, false
+	}
+	child, ok := m[head].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	return LookupPath(child, rest)
+}
+
+// DeletePath removes a dotted key and any parent objects it leaves empty.
+func DeletePath(m map[string]any, key string) {
+	if _, ok := m[key]; ok {
+		delete(m, key)
+		return
+	}
+	head, rest, _ := strings.Cut(key, ".")
+	if child, ok := m[head].(map[string]any); ok {
+		DeletePath(child, rest)
+		if len(child) == 0 {
+			delete(m, head)
+		}
+	}
+}
+
+// SetPath stores value under a dotted key. A literal key that already exists
+// is overwritten; otherwise the path is created through nested objects,
+// replacing any non-object value in the way.
+func SetPath(m map[string]any, key string, value any) {
+	if _, ok := m[key]; ok {
+		m[key] = value
+		return
+	}
+	head, rest, found := strings.Cut(key, ".")
+	if !found {
+		m[key] = value
+		return
+	}
+	child, ok := m[head].(map[string]any)
+	if !ok {
+		child = make(map[string]any)
+		m[head] = child
+	}
+	SetPath(child, rest, value)
+}
+
+// NormalizeValue converts values decoded from binary formats such as msgpack
+// into the shapes encoding/json produces: byte slices become strings and maps
+// with non-string keys get string keys.
+func NormalizeValue(v any) any {
+	switch val := v.(type) {
+	case []byte:
+		return string(val)
+	case map[string]any:
+		for k, child := range val {
+			val[k] = NormalizeValue(child)
+		}
+		return val
+	case map[any]any:
+		out := make(map[string]any, len(val))
+		for k, child := range val {
+			if b, ok := k.([]byte); ok {
+				k = string(b)
+			}
+			out[fmt.Sprint(k)] = NormalizeValue(child)
+		}
+		return out
+	case []any:
+		for i, child := range val {
+			val[i] = NormalizeValue(child)
+		}
+		return val
+	default:
+		return v
+	}
+}
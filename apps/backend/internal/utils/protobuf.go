@@ -0,0 +1,50 @@
+package utils
+
+import "google.golang.org/protobuf/encoding/protowire"
+
+// ProtoField is a single decoded protobuf field. Varint and fixed-width values
+// are stored in Value, length-delimited ones in Bytes.
+type ProtoField struct {
+	Number protowire.Number
+	Type   protowire.Type
+	Value  uint64
+	Bytes  []byte
+}
+
+// ReadProtoFields walks the top-level fields of a protobuf message and calls fn
+// for each of them. It lets the receivers decode well-known wire formats
+// without pulling in generated code for every upstream schema.
+func ReadProtoFields(b []byte, fn func(f ProtoField) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		f := ProtoField{Number: num, Type: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.Value, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			f.Value, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			f.Value = uint64(v)
+		case protowire.BytesType:
+			f.Bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,434 @@
+// Package logless ships application logs to a LogLess server. Entries are
+// buffered in memory, sent in gzip-compressed NDJSON batches to the batch
+// ingestion endpoint and retried with exponential backoff.
+package logless
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Entry mirrors the LogEntry payload accepted by the server. Context values
+// may be any JSON-encodable value, including numbers and nested maps.
+type Entry struct {
+	Level       string         `json:"level"`
+	Message     string         `json:"message"`
+	Timestamp   time.Time      `json:"timestamp"`
+	Service     string         `json:"service,omitempty"`
+	Environment string         `json:"environment,omitempty"`
+	Host        string         `json:"host,omitempty"`
+	TraceID     string         `json:"trace_id,omitempty"`
+	SpanID      string         `json:"span_id,omitempty"`
+	Source      string         `json:"source,omitempty"`
+	Context     map[string]any `json:"context,omitempty"`
+}
+
+type Config struct {
+	// Endpoint is the server base URL, e.g. http://localhost:8080.
+	Endpoint string
+	// Service, Environment and Host are filled in on entries that leave them
+	// empty.
+	Service     string
+	Environment string
+	Host        string
+	// BufferSize is the number of entries held in memory before new ones are dropped.
+	BufferSize int
+	// BatchSize is the maximum number of entries per request.
+	BatchSize int
+	// FlushInterval bounds how long an entry waits before being sent.
+	FlushInterval time.Duration
+	// MaxRetries is the number of retries after the first failed attempt.
+	// Zero uses the default of 5 and a negative value disables retries.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the exponential retry delay.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// DisableCompression sends plain NDJSON instead of gzip.
+	DisableCompression bool
+	// Headers are added to every request, e.g. for authentication.
+	Headers    http.Header
+	HTTPClient *http.Client
+}
+
+// Stats counts what happened to the entries handed to the client.
+type Stats struct {
+	Sent uint64
+	// DroppedBufferFull counts entries rejected because the buffer was full.
+	DroppedBufferFull uint64
+	// DroppedSendFailed counts entries lost after retries were exhausted.
+	DroppedSendFailed uint64
+	// Rejected counts entries the server refused, e.g. for failing validation.
+	Rejected uint64
+}
+
+// Dropped is the total number of entries that never reached the server.
+func (s Stats) Dropped() uint64 {
+	return s.DroppedBufferFull + s.DroppedSendFailed + s.Rejected
+}
+
+var (
+	ErrClosed = errors.New("logless: client is closed")
+	// ErrDropped is returned by Flush when entries queued since the previous
+	// Flush were dropped after retries ran out. Sending them again may work.
+	ErrDropped = errors.New("logless: entries were dropped")
+	// ErrRejected is returned by Flush when the server refused entries queued
+	// since the previous Flush, e.g. for failing validation. Sending them
+	// again would fail the same way.
+	ErrRejected = errors.New("logless: entries were rejected")
+)
+
+type Client struct {
+	config  Config
+	entries chan Entry
+	flushes chan chan error
+	done    chan struct{}
+	stopped chan struct{}
+
+	// mu guards closing, so no sender can enqueue once Close has begun;
+	// senders tracks the ones already past the check.
+	mu      sync.RWMutex
+	closing bool
+	senders sync.WaitGroup
+
+	sent              atomic.Uint64
+	droppedBufferFull atomic.Uint64
+	droppedSendFailed atomic.Uint64
+	rejected          atomic.Uint64
+}
+
+func NewClient(config Config) (*Client, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("logless: endpoint is required")
+	}
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+	if config.BufferSize <= 0 {
+		config.BufferSize = 10000
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 500
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	} else if config.MaxRetries == 0 {
+		config.MaxRetries = 5
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = 100 * time.Millisecond
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 10 * time.Second
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	c := &Client{
+		config:  config,
+		entries: make(chan Entry, config.BufferSize),
+		flushes: make(chan chan error),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go c.run()
+	return c, nil
+}
+
+// Send queues an entry without blocking. When the buffer is full the entry is
+// dropped and counted in Stats.DroppedBufferFull.
+func (c *Client) Send(entry Entry) error {
+	if !c.enter() {
+		c.droppedBufferFull.Add(1)
+		return ErrClosed
+	}
+	defer c.senders.Done()
+
+	select {
+	case c.entries <- c.fill(entry):
+		return nil
+	default:
+		c.droppedBufferFull.Add(1)
+		return errors.New("logless: buffer full, entry dropped")
+	}
+}
+
+// SendWait queues an entry, waiting for room in the buffer instead of
+// dropping it, so a caller that produces faster than the server accepts is
+// slowed down. It fails only when ctx is done or the client is closed.
+func (c *Client) SendWait(ctx context.Context, entry Entry) error {
+	if !c.enter() {
+		return ErrClosed
+	}
+	defer c.senders.Done()
+
+	select {
+	case c.entries <- c.fill(entry):
+		return nil
+	case <-c.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enter registers a sender unless the client is closing. The background
+// sender waits for registered senders before its final drain, so nothing
+// they enqueue is left behind.
+func (c *Client) enter() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closing {
+		return false
+	}
+	c.senders.Add(1)
+	return true
+}
+
+// fill applies the configured defaults to an entry.
+func (c *Client) fill(entry Entry) Entry {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	if entry.Service == "" {
+		entry.Service = c.config.Service
+	}
+	if entry.Environment == "" {
+		entry.Environment = c.config.Environment
+	}
+	if entry.Host == "" {
+		entry.Host = c.config.Host
+	}
+	return entry
+}
+
+// Flush blocks until every entry queued before the call has been sent or
+// dropped, or ctx is done. It returns ErrDropped when any entry queued since
+// the previous Flush could not be sent, and otherwise ErrRejected when the
+// server refused any of them.
+func (c *Client) Flush(ctx context.Context) error {
+	ack := make(chan error, 1)
+	select {
+	case c.flushes <- ack:
+	case <-c.stopped:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-ack:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting entries, sends everything still buffered and waits
+// for the background sender to finish or for ctx to be done.
+func (c *Client) Close(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.closing {
+		c.closing = true
+		close(c.done)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-c.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) Stats() Stats {
+	return Stats{
+		Sent:              c.sent.Load(),
+		DroppedBufferFull: c.droppedBufferFull.Load(),
+		DroppedSendFailed: c.droppedSendFailed.Load(),
+		Rejected:          c.rejected.Load(),
+	}
+}
+
+func (c *Client) run() {
+	defer close(c.stopped)
+
+	ticker := time.NewTicker(c.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Entry, 0, c.config.BatchSize)
+	// dropped and rejected count entries lost since the last Flush call.
+	dropped, rejected := 0, 0
+	flush := func() {
+		if len(batch) > 0 {
+			d, r := c.send(batch)
+			dropped, rejected = dropped+d, rejected+r
+			batch = batch[:0]
+		}
+	}
+	// drain moves everything currently buffered into batches.
+	drain := func() {
+		for {
+			select {
+			case entry := <-c.entries:
+				batch = append(batch, entry)
+				if len(batch) >= c.config.BatchSize {
+					flush()
+				}
+			default:
+				flush()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case entry := <-c.entries:
+			batch = append(batch, entry)
+			if len(batch) >= c.config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case ack := <-c.flushes:
+			drain()
+			switch {
+			case dropped > 0:
+				ack <- ErrDropped
+			case rejected > 0:
+				ack <- ErrRejected
+			}
+			close(ack)
+			dropped, rejected = 0, 0
+		case <-c.done:
+			// Senders that got in before Close may still be enqueueing.
+			c.senders.Wait()
+			drain()
+			return
+		}
+	}
+}
+
+type batchResponse struct {
+	Results []struct {
+		Status string `json:"status"`
+	} `json:"results"`
+}
+
+// send posts one batch, retrying transport errors, 429 and 5xx responses, and
+// returns how many entries were dropped because they could not be sent and
+// how many the server rejected.
+func (c *Client) send(batch []Entry) (dropped, rejected int) {
+	body, err := c.encode(batch)
+	if err != nil {
+		c.droppedSendFailed.Add(uint64(len(batch)))
+		return len(batch), 0
+	}
+
+	backoff := c.config.MinBackoff
+	for attempt := 0; ; attempt++ {
+		response, retryable, err := c.post(body)
+		if err == nil {
+			accepted := uint64(0)
+			for _, result := range response.Results {
+				if result.Status == "accepted" {
+					accepted++
+				}
+			}
+			if len(response.Results) == 0 {
+				accepted = uint64(len(batch))
+			}
+			c.sent.Add(accepted)
+			c.rejected.Add(uint64(len(batch)) - accepted)
+			return 0, len(batch) - int(accepted)
+		}
+		if !retryable || attempt >= c.config.MaxRetries {
+			c.droppedSendFailed.Add(uint64(len(batch)))
+			return len(batch), 0
+		}
+
+		// Jitter keeps a fleet of clients from retrying in lockstep.
+		sleep := time.Duration(rand.Int63n(int64(backoff))) + backoff/2
+		select {
+		case <-time.After(sleep):
+		case <-c.done:
+			// Still retry while closing, but without waiting out the full delay.
+		}
+		backoff = min(backoff*2, c.config.MaxBackoff)
+	}
+}
+
+func (c *Client) encode(batch []Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+
+	var gz *gzip.Writer
+	if !c.config.DisableCompression {
+		gz = gzip.NewWriter(&buf)
+		w = gz
+	}
+
+	encoder := json.NewEncoder(w)
+	for _, entry := range batch {
+		if err := encoder.Encode(entry); err != nil {
+			return nil, err
+		}
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *Client) post(body []byte) (*batchResponse, bool, error) {
+	req, err := http.NewRequest(http.MethodPost, c.config.Endpoint+"/api/logs/batch", bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+	for key, values := range c.config.Headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if !c.config.DisableCompression {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		io.Copy(io.Discard, resp.Body)
+		return nil, true, fmt.Errorf("logless: server returned %s", resp.Status)
+	}
+	if resp.StatusCode >= 400 {
+		io.Copy(io.Discard, resp.Body)
+		return nil, false, fmt.Errorf("logless: server returned %s", resp.Status)
+	}
+
+	var response batchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return &batchResponse{}, false, nil
+	}
+	return &response, false, nil
+}
@@ -0,0 +1,187 @@
+package logless
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeServer answers batch requests with the given statuses in turn, repeating
+// the last one, and records every entry it receives.
+type fakeServer struct {
+	t        *testing.T
+	statuses []int
+	// reject marks entries whose message matches as rejected in a 207.
+	reject string
+
+	mu       sync.Mutex
+	requests int
+	entries  []Entry
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	status := s.statuses[min(s.requests, len(s.statuses)-1)]
+	s.requests++
+	s.mu.Unlock()
+
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+		return
+	}
+
+	body := r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			s.t.Errorf("gzip: %v", err)
+			return
+		}
+		body = gz
+	}
+	var results []map[string]string
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			s.t.Errorf("decode entry: %v", err)
+			return
+		}
+		result := "accepted"
+		if s.reject != "" && entry.Message == s.reject {
+			result = "rejected"
+		} else {
+			s.mu.Lock()
+			s.entries = append(s.entries, entry)
+			s.mu.Unlock()
+		}
+		results = append(results, map[string]string{"status": result})
+	}
+	json.NewEncoder(w).Encode(map[string]any{"results": results})
+}
+
+func (s *fakeServer) received() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Entry(nil), s.entries...)
+}
+
+func newTestClient(t *testing.T, server *fakeServer, config Config) *Client {
+	t.Helper()
+	server.t = t
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	config.Endpoint = ts.URL
+	config.MinBackoff = time.Millisecond
+	config.MaxBackoff = time.Millisecond
+	config.FlushInterval = time.Hour
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close(t.Context()) })
+	return client
+}
+
+func TestClientFlush(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		reject     string
+		maxRetries int
+		compress   bool
+		wantErr    error
+		wantStats  Stats
+	}{
+		{name: "accepted", statuses: []int{200}, wantStats: Stats{Sent: 2}},
+		{name: "gzip", statuses: []int{200}, compress: true, wantStats: Stats{Sent: 2}},
+		{name: "retried after 503", statuses: []int{503, 429, 200}, wantStats: Stats{Sent: 2}},
+		{name: "retries exhausted", statuses: []int{503}, maxRetries: 2, wantErr: ErrDropped, wantStats: Stats{DroppedSendFailed: 2}},
+		{name: "client error not retried", statuses: []int{400, 200}, wantErr: ErrDropped, wantStats: Stats{DroppedSendFailed: 2}},
+		{name: "partly rejected", statuses: []int{200}, reject: "b", wantErr: ErrRejected, wantStats: Stats{Sent: 1, Rejected: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &fakeServer{statuses: tt.statuses, reject: tt.reject}
+			client := newTestClient(t, server, Config{
+				Service:            "api",
+				MaxRetries:         tt.maxRetries,
+				DisableCompression: !tt.compress,
+			})
+
+			client.Send(Entry{Level: "info", Message: "a"})
+			client.Send(Entry{Level: "info", Message: "b"})
+			if err := client.Flush(t.Context()); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Flush = %v, want %v", err, tt.wantErr)
+			}
+			if got := client.Stats(); got != tt.wantStats {
+				t.Errorf("Stats = %+v, want %+v", got, tt.wantStats)
+			}
+			for _, entry := range server.received() {
+				if entry.Service != "api" || entry.Timestamp.IsZero() {
+					t.Errorf("defaults not applied: %+v", entry)
+				}
+			}
+
+			// The error covers only entries since the previous Flush.
+			if err := client.Flush(t.Context()); err != nil {
+				t.Errorf("second Flush = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestClientSendAfterClose(t *testing.T) {
+	client := newTestClient(t, &fakeServer{statuses: []int{200}}, Config{})
+	if err := client.Close(t.Context()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := client.Send(Entry{Message: "late"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Send = %v, want ErrClosed", err)
+	}
+	if err := client.SendWait(t.Context(), Entry{Message: "late"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("SendWait = %v, want ErrClosed", err)
+	}
+	if err := client.Flush(t.Context()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Flush = %v, want ErrClosed", err)
+	}
+}
+
+// Every entry a sender managed to queue must be sent by Close, even while
+// Close races with the senders.
+func TestClientCloseKeepsQueuedEntries(t *testing.T) {
+	server := &fakeServer{statuses: []int{200}}
+	client := newTestClient(t, server, Config{BufferSize: 100000})
+
+	var queued atomic.Uint64
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if err := client.SendWait(t.Context(), Entry{Message: "x"}); err != nil {
+					return
+				}
+				queued.Add(1)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := client.Close(t.Context()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	wg.Wait()
+
+	if got, want := uint64(len(server.received())), queued.Load(); got != want {
+		t.Errorf("server received %d entries, %d were queued", got, want)
+	}
+}
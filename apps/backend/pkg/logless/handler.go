@@ -0,0 +1,190 @@
+package logless
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"runtime"
+	"time"
+)
+
+type HandlerOptions struct {
+	// Level is the minimum level shipped; slog.LevelInfo when nil.
+	Level slog.Leveler
+	// AddSource records the caller's file and line in the context.
+	AddSource bool
+}
+
+// Handler is a slog.Handler that ships records through a Client. Attributes
+// become context keys with their types preserved; groups become nested
+// objects.
+type Handler struct {
+	client *Client
+	opts   HandlerOptions
+	attrs  map[string]any
+	groups []string
+}
+
+func NewHandler(client *Client, opts *HandlerOptions) *Handler {
+	h := &Handler{client: client, attrs: map[string]any{}}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.Level == nil {
+		h.opts.Level = slog.LevelInfo
+	}
+	return h
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.opts.Level.Level()
+}
+
+func (h *Handler) Handle(_ context.Context, record slog.Record) error {
+	context := copyAttrs(h.attrs)
+	if record.NumAttrs() > 0 {
+		group := groupMap(context, h.groups)
+		record.Attrs(func(attr slog.Attr) bool {
+			addAttr(group, attr)
+			return true
+		})
+	}
+
+	if h.opts.AddSource && record.PC != 0 {
+		frames := runtime.CallersFrames([]uintptr{record.PC})
+		frame, _ := frames.Next()
+		context["source"] = fmt.Sprintf("%s:%d", frame.File, frame.Line)
+	}
+
+	entry := Entry{
+		Level:     levelName(record.Level),
+		Message:   record.Message,
+		Timestamp: record.Time,
+	}
+	if len(context) > 0 {
+		entry.Context = context
+	}
+	return h.client.Send(entry)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := h.clone()
+	for _, attr := range attrs {
+		addAttr(groupMap(clone.attrs, clone.groups), attr)
+	}
+	return clone
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := h.clone()
+	clone.groups = append(clone.groups, name)
+	return clone
+}
+
+func (h *Handler) clone() *Handler {
+	groups := append([]string(nil), h.groups...)
+	return &Handler{client: h.client, opts: h.opts, attrs: copyAttrs(h.attrs), groups: groups}
+}
+
+// copyAttrs deep-copies the nested group maps so handlers derived with
+// WithAttrs or WithGroup never share them.
+func copyAttrs(attrs map[string]any) map[string]any {
+	out := make(map[string]any, len(attrs))
+	for k, v := range attrs {
+		if group, ok := v.(map[string]any); ok {
+			v = copyAttrs(group)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// groupMap returns the nested map for the open groups, creating it as needed.
+func groupMap(context map[string]any, groups []string) map[string]any {
+	for _, name := range groups {
+		child, ok := context[name].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			context[name] = child
+		}
+		context = child
+	}
+	return context
+}
+
+func addAttr(context map[string]any, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		group := context
+		if attr.Key != "" {
+			group = groupMap(context, []string{attr.Key})
+		}
+		for _, child := range attr.Value.Group() {
+			addAttr(group, child)
+		}
+		return
+	}
+
+	context[attr.Key] = attrValue(attr.Value)
+}
+
+// attrValue converts a slog value into one that encodes as the matching JSON
+// type. Durations are sent as nanoseconds like slog.JSONHandler does, and
+// values that cannot be encoded fall back to their string form.
+func attrValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		if f := v.Float64(); !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration().Nanoseconds()
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+		if data, err := json.Marshal(v.Any()); err == nil {
+			return json.RawMessage(data)
+		}
+	}
+	return v.String()
+}
+
+// levelName maps slog levels, custom ones included, onto the canonical level
+// names LogLess stores. A level counts as the nearest standard level at or
+// below it, so warn+2 is warn; levels under debug are trace and levels four
+// or more above error are fatal.
+func levelName(level slog.Level) string {
+	switch {
+	case level < slog.LevelDebug:
+		return "trace"
+	case level < slog.LevelInfo:
+		return "debug"
+	case level < slog.LevelWarn:
+		return "info"
+	case level < slog.LevelError:
+		return "warn"
+	case level < slog.LevelError+4:
+		return "error"
+	}
+	return "fatal"
+}
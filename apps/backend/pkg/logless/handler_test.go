@@ -0,0 +1,97 @@
+package logless
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name  string
+		log   func(*slog.Logger)
+		level string
+		want  string
+	}{
+		{
+			name:  "typed attributes",
+			log:   func(l *slog.Logger) { l.Info("m", "n", 3, "ok", true, "d", time.Second, "err", errors.New("boom")) },
+			level: "info",
+			want:  `{"d":1000000000,"err":"boom","n":3,"ok":true}`,
+		},
+		{
+			name:  "groups nest",
+			log:   func(l *slog.Logger) { l.WithGroup("req").With("id", "r1").Warn("m", slog.Group("user", "id", 7)) },
+			level: "warn",
+			want:  `{"req":{"id":"r1","user":{"id":7}}}`,
+		},
+		{
+			name:  "empty and inline groups",
+			log:   func(l *slog.Logger) { l.WithGroup("").Error("m", slog.Group("", "a", 1), slog.Group("empty")) },
+			level: "error",
+			want:  `{"a":1}`,
+		},
+		{
+			name:  "custom level",
+			log:   func(l *slog.Logger) { l.Log(t.Context(), slog.LevelInfo+2, "m") },
+			level: "info",
+			want:  `null`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &fakeServer{statuses: []int{200}}
+			client := newTestClient(t, server, Config{})
+			tt.log(slog.New(NewHandler(client, nil)))
+			if err := client.Flush(t.Context()); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+
+			received := server.received()
+			if len(received) != 1 {
+				t.Fatalf("received %d entries, want 1", len(received))
+			}
+			if received[0].Level != tt.level {
+				t.Errorf("level = %q, want %q", received[0].Level, tt.level)
+			}
+			if got, _ := json.Marshal(received[0].Context); string(got) != tt.want {
+				t.Errorf("context = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerWithAttrsDoesNotShare(t *testing.T) {
+	base := NewHandler(nil, nil).WithGroup("g").WithAttrs([]slog.Attr{slog.Int("a", 1)}).(*Handler)
+	derived := base.WithAttrs([]slog.Attr{slog.Int("b", 2)}).(*Handler)
+	if _, ok := base.attrs["g"].(map[string]any)["b"]; ok {
+		t.Error("WithAttrs on a derived handler changed its parent")
+	}
+	if got := derived.attrs["g"].(map[string]any); got["a"] != int64(1) || got["b"] != int64(2) {
+		t.Errorf("derived attrs = %v", got)
+	}
+}
+
+func TestLevelName(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  string
+	}{
+		{slog.LevelDebug - 4, "trace"},
+		{slog.LevelDebug, "debug"},
+		{slog.LevelInfo - 1, "debug"},
+		{slog.LevelInfo, "info"},
+		{slog.LevelInfo + 2, "info"},
+		{slog.LevelWarn, "warn"},
+		{slog.LevelError, "error"},
+		{slog.LevelError + 2, "error"},
+		{slog.LevelError + 4, "fatal"},
+	}
+	for _, tt := range tests {
+		if got := levelName(tt.level); got != tt.want {
+			t.Errorf("levelName(%v) = %q, want %q", tt.level, got, tt.want)
+		}
+	}
+}
@@ -183,7 +183,7 @@ const LogTableRow = ({
           {log.context?.userId ? (
             <div className="flex items-center gap-1">
               <User size={12} className="text-gray-400" />
-              {log.context.userId}
+              {typeof log.context.userId === 'object' ? JSON.stringify(log.context.userId) : String(log.context.userId)}
             </div>
           ) : (
             <span className="text-gray-400">-</span>
@@ -263,7 +263,8 @@ export default function LogTable({ logs, onLogSelect }) {
     ]);
     
     allKeys.forEach(key => {
-      if (context1?.[key] !== context2?.[key]) {
+      // Context values can be nested objects, so compare their encoded form.
+      if (JSON.stringify(context1?.[key]) !== JSON.stringify(context2?.[key])) {
         diff[key] = context2?.[key];
       }
     });
@@ -292,7 +293,8 @@ export default function LogTable({ logs, onLogSelect }) {
     if (existingIndex !== -1) {
       // Group with existing log
       const existing = acc[existingIndex];
-      existing.count = (existing.count || 1) + 1;
+      // Entries collapsed by server-side deduplication carry their own count.
+      existing.count = (existing.count || 1) + (log.repeat_count || 1);
       existing.timestamp = log.timestamp;
       
       if (!existing.firstOccurrence) {
@@ -312,8 +314,8 @@ export default function LogTable({ logs, onLogSelect }) {
       // Create new log entry
       acc.push({
         ...log,
-        count: 1,
-        firstOccurrence: log.timestamp
+        count: log.repeat_count || 1,
+        firstOccurrence: log.first_seen || log.timestamp
       });
     }
     